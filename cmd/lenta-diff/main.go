@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"testJob/internal/lenta"
+	"testJob/internal/storage"
+)
+
+// main — отчёт об изменениях цен между двумя обходами.
+// Сравнивает две выгрузки (-old, -new) или два запуска из базы (-db),
+// товары сопоставляются по ID и магазину.
+func main() {
+	oldPath := flag.String("old", "", "Прошлая выгрузка lenta-parser: json, jsonl или csv с -full")
+	newPath := flag.String("new", "", "Новая выгрузка")
+	dbPath := flag.String("db", "", "SQLite-база lenta-parser: сравнить запуски вместо файлов")
+	oldRun := flag.Int64("old-run", 0, "ID прошлого запуска (0 — предпоследний завершённый)")
+	newRun := flag.Int64("new-run", 0, "ID нового запуска (0 — последний завершённый)")
+	output := flag.String("output", "", "Файл отчёта (пусто — stdout)")
+	format := flag.String("format", "", "Формат отчёта: csv, json, md (пусто — по расширению -output, иначе md)")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	var oldSnap, newSnap lenta.Snapshot
+	var err error
+
+	if *dbPath != "" {
+		oldSnap, newSnap, err = loadRuns(ctx, *dbPath, *oldRun, *newRun)
+	} else {
+		if *oldPath == "" || *newPath == "" {
+			log.Fatal("Укажите -old и -new или -db")
+		}
+		if oldSnap, err = lenta.LoadSnapshot(*oldPath); err == nil {
+			newSnap, err = lenta.LoadSnapshot(*newPath)
+		}
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	diffFormat := lenta.DiffFormatFromPath(*output)
+	if *format != "" {
+		if diffFormat, err = lenta.ParseDiffFormat(*format); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	changes := lenta.DiffSnapshots(oldSnap, newSnap)
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := lenta.WriteDiff(w, changes, diffFormat); err != nil {
+		log.Fatal("Ошибка записи отчёта:", err)
+	}
+
+	counts := make(map[lenta.ChangeKind]int)
+	for _, c := range changes {
+		counts[c.Kind]++
+	}
+	log.Printf("Было %d, стало %d позиций: новых %d, пропало %d, подорожало %d, подешевело %d, скидки +%d/-%d",
+		len(oldSnap), len(newSnap), counts[lenta.ChangeNew], counts[lenta.ChangeDelisted],
+		counts[lenta.ChangePriceUp], counts[lenta.ChangePriceDown],
+		counts[lenta.ChangeDiscountStart], counts[lenta.ChangeDiscountEnd])
+}
+
+// loadRuns читает цены двух запусков из базы.
+// Незаданные ID берутся из последних завершённых запусков. Частичные, упавшие
+// и прерванные запуски пропускаются: товары необойдённых категорий
+// выглядели бы снятыми с продажи.
+
+func loadRuns(ctx context.Context, path string, oldID, newID int64) (lenta.Snapshot, lenta.Snapshot, error) {
+	db, err := storage.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	runs, err := db.Runs(ctx, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	explicit := map[int64]bool{oldID: oldID != 0, newID: newID != 0}
+	found := make(map[int64]bool)
+	for _, r := range runs {
+		found[r.ID] = true
+		if explicit[r.ID] && r.Status != storage.RunDone {
+			log.Printf("[WARN] Запуск #%d в статусе %s — часть товаров может оказаться в «пропавших»", r.ID, r.Status)
+		}
+		if r.Status != storage.RunDone || r.ID == oldID || r.ID == newID {
+			continue
+		}
+		switch {
+		case newID == 0:
+			newID = r.ID
+		case oldID == 0 && r.ID < newID:
+			oldID = r.ID
+		}
+	}
+	// Несуществующий запуск дал бы пустой снимок, и все товары
+	// оказались бы новыми или пропавшими.
+	for id, ok := range explicit {
+		if ok && !found[id] {
+			return nil, nil, fmt.Errorf("запуск #%d не найден в базе", id)
+		}
+	}
+	if oldID == 0 || newID == 0 {
+		return nil, nil, errors.New("в базе меньше двух завершённых запусков — укажите -old-run и -new-run")
+	}
+	log.Printf("Сравнение запусков #%d → #%d", oldID, newID)
+
+	oldSnap, err := db.RunSnapshot(ctx, oldID)
+	if err != nil {
+		return nil, nil, err
+	}
+	newSnap, err := db.RunSnapshot(ctx, newID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldSnap, newSnap, nil
+}
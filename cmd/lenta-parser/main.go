@@ -1,255 +1,727 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"math/rand"
-	"net/http"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/playwright-community/playwright-go"
 	"testJob/internal/lenta"
+	"testJob/internal/storage"
 )
 
 // main — точка входа.
 // 1. Инициализирует конфиг и HTTP-клиент с uTLS fingerprint.
-// 2. Прогревает сессию через Playwright для получения anti-bot cookies.
-// 3. Извлекает session token.
-// 4. Итерируется по категориям и пагинирует API.
-// 5. Экспортирует результат в CSV.
+// 2. Прогревает сессию через SessionWarmer (Playwright): anti-bot cookies и session token.
+// 3. Строит дерево категорий и обходит листья пулом воркеров с пагинацией API.
+// 4. Экспортирует результат в CSV, JSON или JSONL и, с -db, пишет историю цен в SQLite.
+// 5. По SIGINT/SIGTERM останавливает обход, сохраняя собранное и checkpoint.
 func main() {
-	proxy := flag.String("proxy", "", "URL прокси (пример: http://user:pass@ip:port)")
-	output := flag.String("output", "products.csv", "Путь к файлу выгрузки (csv или json)")
+	proxy := flag.String("proxy", "", "URL прокси (пример: http://user:pass@ip:port или socks5h://user:pass@ip:port)")
+	profileName := flag.String("profile", lenta.DefaultProfileName, "Профиль браузера: "+strings.Join(lenta.ProfileNames(), ", "))
+	proxies := flag.String("proxies", "", "Список прокси через запятую для ротации")
+	proxyFile := flag.String("proxy-file", "", "Файл со списком прокси (по одному на строку)")
+	proxyStrategy := flag.String("proxy-strategy", "round-robin", "Выбор прокси: round-robin, random, least-failures")
+	proxyCooldown := flag.Duration("proxy-cooldown", 5*time.Minute, "Пауза для прокси после серии ошибок")
+	proxyPin := flag.Bool("proxy-pin", true, "Закреплять прокси из пула за прогретой сессией")
+	output := flag.String("output", "products.csv", "Путь к файлу выгрузки (csv, json или jsonl)")
+	format := flag.String("format", "", "Формат выгрузки: csv, json, jsonl (пусто — по расширению -output)")
+	checkpointPath := flag.String("checkpoint", "crawl.checkpoint.json", "Файл checkpoint-а длинного обхода (пусто — без checkpoint-а)")
+	resume := flag.Bool("resume", false, "Продолжить прерванный обход с места остановки по -checkpoint")
+	dbPath := flag.String("db", "", "SQLite-база для истории цен (пусто — не сохранять)")
+	fullCSV := flag.Bool("full", false, "CSV со всеми полями товара (id, бренд, наличие, категория…); без флага — name;price;url")
+	rawJSON := flag.Bool("raw", false, "В json/jsonl писать исходный JSON товара из API со всеми полями")
+	sessionFile := flag.String("session", "session.json", "Файл сохранённой сессии (пусто — всегда прогревать браузером)")
+	rps := flag.Float64("rps", 0.4, "Запросов в секунду на клиента (0 — без ограничения)")
+	burst := flag.Int("burst", 1, "Запас токенов rate limiter-а")
+	delayDist := flag.String("delay-dist", "uniform", "Распределение случайной паузы: none, uniform, normal, lognormal")
+	delayMin := flag.Duration("delay-min", 0, "Минимальная случайная пауза перед запросом")
+	delayMax := flag.Duration("delay-max", 3*time.Second, "Максимальная случайная пауза перед запросом")
+	roots := flag.String("root", "", "ID корневых категорий через запятую, от которых строится дерево (пусто — весь каталог)")
+	subtree := flag.String("subtree", "", "Обойти только поддерево категории (ID или slug)")
+	discoverDepth := flag.Int("discover-depth", 0, "Глубина обхода дерева категорий (0 — без ограничения)")
+	retries := flag.Int("retries", 4, "Максимум попыток на запрос (сеть, TLS, прокси, 5xx, 429)")
+	workers := flag.Int("workers", 1, "Сколько категорий обходить одновременно (общий лимит -rps делится между ними)")
+	ordered := flag.Bool("ordered", true, "Писать товары в порядке категорий (false — по мере получения)")
+	maxRefresh := flag.Int("max-refresh", 3, "Сколько раз за запуск можно перепрогреть сессию при 401/403")
+	sortName := flag.String("sort", "popular", "Сортировка: "+strings.Join(lenta.SortNames(), ", "))
+	priceRange := flag.String("price", "", "Диапазон цены в рублях: 50..200, 50.. или ..200")
+	weightRange := flag.String("weight", "", "Диапазон веса: от..до")
+	region := flag.String("region", lenta.DefaultRegion, "Регион (x-domain): moscow, spb, kazan…")
+	delivery := flag.String("delivery", "pickup", "Способ получения: pickup или courier")
+	retailBrand := flag.String("retail-brand", lenta.DefaultRetailBrand, "Торговая сеть (x-retail-brand)")
+	store := flag.String("store", "", "Магазин: ID или часть адреса (пусто — магазин региона по умолчанию)")
+	listStores := flag.Bool("list-stores", false, "Вывести магазины региона и выйти")
+	schemaFile := flag.String("schema", "", "Файл baseline схемы ответов API: включает проверку дрейфа схемы")
+	schemaStrict := flag.Bool("schema-strict", false, "Завершать запуск с ошибкой при любом расхождении схемы")
+	schemaUpdate := flag.Bool("schema-update", false, "Записать схему этого запуска как новый baseline в -schema")
+	var filters, facets, compare listFlag
+	flag.Var(&filters, "filter", "Checkbox-фильтр key=value[,value] или key (переключатель); можно повторять")
+	flag.Var(&facets, "facet", "Multicheckbox-фильтр key=value[,value]; можно повторять")
+	flag.Var(&compare, "compare", "Сравнить цены в целях [метка=]регион[:магазин][:способ]; можно повторять или через запятую")
 	flag.Parse()
 
+	// SIGINT/SIGTERM отменяют контекст: запросы прерываются, собранное
+	// дописывается в выгрузку, базу и checkpoint, браузер и соединения закрываются.
+	// Повторный сигнал завершает процесс сразу.
+	ctx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	context.AfterFunc(ctx, func() {
+		stopSignals()
+		log.Println("Получен сигнал остановки — сохраняем собранное (повторный сигнал прервёт сразу)")
+	})
+	// Записи в базу доводятся до конца и после сигнала.
+	dbCtx := context.WithoutCancel(ctx)
+
+	// Профиль браузера задаёт TLS fingerprint, UA и client hints
+	// одинаково для uTLS-клиента и Playwright.
+	profile, err := lenta.LookupProfile(*profileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	deliveryMode, err := lenta.ParseDeliveryMode(*delivery)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Конфигурация клиента.
 	// DeviceID и UserSessionID эмулируют браузерную сессию.
 	// Без них API может возвращать 401/403.
 	cfg := &lenta.Config{
 		ProxyURL:      *proxy,
 		Domain:        "lenta.com",
+		Profile:       profile,
 		DeviceID:      uuid.New().String(),
 		UserSessionID: uuid.New().String(),
+		Region:        *region,
+		DeliveryMode:  deliveryMode,
+		RetailBrand:   *retailBrand,
 	}
 
-	// Создаём HTTP-клиент с кастомным транспортом (uTLS + HTTP/2).
-	// Это необходимо для эмуляции TLS fingerprint браузера.
+	retryPolicy := lenta.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = *retries
+	cfg.Retry = &retryPolicy
 
-	client, err := lenta.NewClient(cfg)
-	if err != nil {
-		log.Fatal("Ошибка создания клиента:", err)
+	// Пул прокси: -proxies и -proxy-file объединяются.
+	var proxyList []string
+	for _, p := range strings.Split(*proxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxyList = append(proxyList, p)
+		}
+	}
+	if *proxyFile != "" {
+		fromFile, err := lenta.LoadProxyList(*proxyFile)
+		if err != nil {
+			log.Fatal("Ошибка чтения списка прокси:", err)
+		}
+		proxyList = append(proxyList, fromFile...)
+	}
+	if len(proxyList) > 0 {
+		strategy, err := lenta.ParseProxyStrategy(*proxyStrategy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		pool, err := lenta.NewProxyPool(proxyList, strategy)
+		if err != nil {
+			log.Fatal("Ошибка создания пула прокси:", err)
+		}
+		pool.Cooldown = *proxyCooldown
+		cfg.ProxyPool = pool
+		cfg.PinProxy = *proxyPin
 	}
 
-	log.Println("Прогрев сессии через playwright-go...")
-
-	// Запускаем headless браузер для прохождения anti-bot (Qrator).
-	// После прогрева получаем валидные cookies.
-	pw, err := playwright.Run()
-	if err != nil {
-		log.Fatal("Ошибка запуска playwright:", err)
-	}
-	defer pw.Stop()
-
-	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-		Args: []string{
-			"--disable-blink-features=AutomationControlled",
-			"--no-sandbox",
-			"--disable-infobars",
-			"--window-size=1920,1080",
-			"--disable-gpu",
-		},
-	})
+	// Темп запросов задаёт rate limiter клиента: токены + «человеческая» пауза.
+	// Адаптивный режим сам притормаживает при 429 и anti-bot ответах.
+	// С пулом прокси лимит действует на каждый выходной IP отдельно.
+	dist, err := lenta.ParseDelayDist(*delayDist)
 	if err != nil {
-		log.Fatal("Ошибка запуска браузера:", err)
+		log.Fatal(err)
+	}
+	pace := lenta.RateLimit{
+		RPS:   *rps,
+		Burst: *burst,
+		Delay: lenta.Delay{Dist: dist, Min: *delayMin, Max: *delayMax},
+	}
+	cfg.RateLimit = &lenta.RateLimitConfig{Global: pace, Adaptive: true}
+	if cfg.ProxyPool != nil {
+		cfg.RateLimit.Global = lenta.RateLimit{}
+		cfg.RateLimit.PerProxy = map[string]lenta.RateLimit{"*": pace}
 	}
-	defer browser.Close()
 
-	context, err := browser.NewContext(playwright.BrowserNewContextOptions{
-		UserAgent: playwright.String("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36"),
-		Viewport: &playwright.Size{
-			Width:  1920,
-			Height: 1080,
-		},
-		Locale: playwright.String("ru-RU"),
-	})
-	if err != nil {
-		log.Fatal("Ошибка создания контекста:", err)
+	var exportFormat lenta.ExportFormat
+	if *format != "" {
+		if exportFormat, err = lenta.ParseExportFormat(*format); err != nil {
+			log.Fatal(err)
+		}
 	}
-	defer context.Close()
 
-	page, err := context.NewPage()
+	// Фильтры и сортировка выдачи — одни и те же для всех категорий.
+	query, err := buildQuery(*sortName, *priceRange, *weightRange, filters, facets)
 	if err != nil {
-		log.Fatal("Ошибка создания страницы:", err)
+		log.Fatal(err)
 	}
 
-	// Переходим на главную страницу,
-	// чтобы инициировать anti-bot проверку и получить первичные cookies.
+	var targets []lenta.StoreTarget
+	for _, list := range compare {
+		for _, spec := range strings.Split(list, ",") {
+			if strings.TrimSpace(spec) == "" {
+				continue
+			}
+			t, err := lenta.ParseStoreTarget(spec)
+			if err != nil {
+				log.Fatal(err)
+			}
+			targets = append(targets, t)
+		}
+	}
 
-	_, err = page.Goto("https://lenta.com/", playwright.PageGotoOptions{
-		Timeout:   playwright.Float(60000),
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-	})
-	if err != nil {
-		log.Printf("Ошибка перехода на главную: %v", err)
+	// Проверка схемы: расхождения с моделями и с baseline прошлых запусков.
+	if *schemaFile != "" || *schemaStrict {
+		var baseline map[string]lenta.Schema
+		if *schemaFile != "" && !*schemaUpdate {
+			if baseline, err = lenta.LoadSchemaBaseline(*schemaFile); err != nil {
+				log.Fatal(err)
+			}
+		}
+		cfg.Schema = lenta.NewSchemaTracker(baseline, *schemaStrict)
 	}
-	time.Sleep(6 * time.Second)
 
-	// Переход в категорию нужен для полной инициализации frontend-сессии.
-	// Некоторые токены появляются только после загрузки каталога.
+	if len(targets) > 0 && exportFormat != lenta.FormatCSV && (exportFormat != "" || lenta.FormatFromPath(*output) != lenta.FormatCSV) {
+		log.Fatal("Сравнение цен выгружается только в CSV")
+	}
+	if len(targets) > 0 && *store != "" {
+		log.Fatal("-store не действует в режиме -compare — укажите магазин в цели: регион:магазин")
+	}
 
-	_, err = page.Goto("https://lenta.com/catalog/moloko-128/", playwright.PageGotoOptions{
-		Timeout:   playwright.Float(60000),
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-	})
-	if err != nil {
-		log.Printf("Ошибка перехода в категорию: %v", err)
+	// Checkpoint прерванного обхода: продолжать можно только с теми же параметрами,
+	// иначе выгрузка смешает разные выборки.
+	params := strings.Join([]string{
+		*region, string(deliveryMode), *retailBrand, *store, *roots, *subtree, strconv.Itoa(*discoverDepth),
+		*sortName, *priceRange, *weightRange, filters.String(), facets.String(),
+	}, "|")
+	var cp *lenta.Checkpoint
+	if *resume {
+		if *checkpointPath == "" || len(targets) > 0 {
+			log.Fatal("-resume работает только с -checkpoint и без -compare")
+		}
+		if cp, err = lenta.LoadCheckpoint(*checkpointPath); err != nil {
+			log.Fatal("Ошибка чтения checkpoint-а:", err)
+		}
+		if cp.Params != params {
+			log.Fatal("Checkpoint записан с другими параметрами обхода — запустите без -resume")
+		}
+		log.Printf("Продолжение обхода: завершено категорий %d, собрано товаров %d", len(cp.Completed), cp.Items)
 	}
-	time.Sleep(8 * time.Second)
 
-	// Ждём появления карточек товаров
-	err = page.Locator(`.card-name_content`).First().WaitFor(
-		playwright.LocatorWaitForOptions{
-			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(30000),
-		},
-	)
+	// База открывается до прогрева браузера, чтобы ошибка пути всплыла сразу.
+	var db *storage.DB
+	if *dbPath != "" {
+		if len(targets) > 0 {
+			log.Println("[WARN] -db не используется в режиме -compare")
+		} else if db, err = storage.Open(*dbPath); err != nil {
+			log.Fatal("Ошибка открытия базы:", err)
+		} else {
+			defer db.Close()
+		}
+	}
+
+	// Создаём HTTP-клиент с кастомным транспортом (uTLS + HTTP/2).
+	// Это необходимо для эмуляции TLS fingerprint браузера.
 
+	client, err := lenta.NewClient(cfg)
 	if err != nil {
-		log.Printf("Карточки товаров не появились за 30 сек: %v", err)
+		log.Fatal("Ошибка создания клиента:", err)
 	}
+	defer client.Close()
 
-	// Эмуляция пользовательского поведения.
-	// Скролл помогает завершить anti-bot challenge.
+	// Восстанавливаем сессию из файла, если она ещё жива.
+	// Иначе прогреваем в headless браузере для прохождения anti-bot (Qrator)
+	// и переносим cookies и токены в HTTP-клиент.
+	// После этого API-запросы будут проходить как из браузера.
 
-	_, err = page.Evaluate(`() => { window.scrollBy(0, document.body.scrollHeight / 2); }`, nil)
-	time.Sleep(4 * time.Second)
+	warmer := lenta.NewPlaywrightWarmer(profile)
+	warmer.DeviceID = cfg.DeviceID
+	warmer.UserSessionID = cfg.UserSessionID
 
-	_, err = page.Evaluate(`() => { window.scrollBy(0, document.body.scrollHeight); }`, nil)
-	time.Sleep(4 * time.Second)
+	if err := lenta.RestoreOrWarm(ctx, client, warmer, *sessionFile); err != nil {
+		if errors.Is(err, lenta.ErrCanceled) {
+			log.Println("Остановлено до начала обхода")
+			return
+		}
+		log.Fatal(err)
+	}
 
-	// Получаем cookies из браузера.
-	// Они будут перенесены в http.Client для API-запросов.
+	// Если посреди обхода истекут cookies Qrator или токен, клиент
+	// перепрогреет сессию тем же warmer-ом и повторит запрос.
+	client.SetSessionWarmer(warmer, *maxRefresh, func(s *lenta.Session) {
+		if *sessionFile == "" {
+			return
+		}
+		if err := lenta.SaveSession(*sessionFile, s); err != nil {
+			log.Printf("Не удалось сохранить сессию: %v", err)
+		}
+	})
 
-	cookies, err := context.Cookies("https://lenta.com")
-	if err != nil {
-		log.Fatal("Ошибка получения куки:", err)
+	// Магазин: по ID сразу, по адресу — через список магазинов региона.
+	if *listStores {
+		stores, err := lenta.ListStores(ctx, client, "")
+		if err != nil {
+			log.Fatal("Ошибка получения списка магазинов:", err)
+		}
+		printStores(stores)
+		return
+	}
+	if *store != "" {
+		id, err := resolveStore(ctx, client, *store)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.SetStore(id)
+		log.Printf("Регион %s, магазин %d, получение %s", client.Region(), id, deliveryMode)
 	}
 
-	var httpCookies []*http.Cookie
-	for _, c := range cookies {
-		sameSite := http.SameSiteLaxMode
+	if cfg.SessionToken != "" {
+		log.Printf("Utk_SessionToken установлен: %s...", cfg.SessionToken[:min(16, len(cfg.SessionToken))])
+	} else {
+		log.Println("[WARN] Utk_SessionToken НЕ найден — высокая вероятность 403/401")
+	}
 
-		if c.SameSite != nil {
-			switch c.SameSite {
-			case playwright.SameSiteAttributeStrict:
-				sameSite = http.SameSiteStrictMode
-			case playwright.SameSiteAttributeLax:
-				sameSite = http.SameSiteLaxMode
-			case playwright.SameSiteAttributeNone:
-				sameSite = http.SameSiteNoneMode
-			}
+	// Строим дерево категорий от корней и обходим листья —
+	// всё дерево или только выбранное поддерево.
+	var rootIDs []int
+	for _, r := range strings.Split(*roots, ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
 		}
-
-		httpCookies = append(httpCookies, &http.Cookie{
-			Name:     c.Name,
-			Value:    c.Value,
-			Domain:   c.Domain,
-			Path:     c.Path,
-			Expires:  time.Unix(int64(c.Expires), 0),
-			Secure:   c.Secure,
-			HttpOnly: c.HttpOnly,
-			SameSite: sameSite,
-		})
+		id, err := strconv.Atoi(r)
+		if err != nil {
+			log.Fatalf("Неверный ID категории %q в -root", r)
+		}
+		rootIDs = append(rootIDs, id)
 	}
 
-	// Переносим cookies в cookiejar HTTP-клиента.
-	// После этого API-запросы будут проходить как из браузера.
+	// При продолжении дерево берётся из checkpoint-а — без повторного обхода.
+	var tree *lenta.CategoryTree
+	if cp != nil && len(cp.Tree) > 0 {
+		tree = lenta.NewCategoryTree(cp.Tree)
+	} else {
+		tree, err = lenta.DiscoverCategories(ctx, client, rootIDs, lenta.DiscoverOptions{MaxDepth: *discoverDepth})
+		// Неполное дерево не должно попасть в checkpoint: продолжение
+		// обошло бы только найденные категории и завершилось бы как полное.
+		if ctx.Err() != nil {
+			log.Println("Остановлено при построении дерева категорий")
+			return
+		}
+		if err != nil {
+			log.Fatalf("Ошибка построения дерева категорий: %v", err)
+		}
+	}
+	log.Printf("Найдено категорий: %d", tree.Len())
 
-	client.SetCookies(httpCookies)
+	categories := tree.Leaves()
+	if *subtree != "" {
+		node := tree.Lookup(*subtree)
+		if node == nil {
+			log.Fatalf("Категория %q не найдена в дереве", *subtree)
+		}
+		categories = node.Leaves()
+	}
+	log.Printf("Листовых категорий к обходу: %d", len(categories))
+
+	// Режим сравнения: те же категории в нескольких регионах и магазинах,
+	// результат — широкая таблица цен.
+	if len(targets) > 0 {
+		var ids []int
+		for _, cat := range categories {
+			ids = append(ids, cat.ID)
+		}
+		cmp, err := lenta.ComparePrices(ctx, client, targets, ids, lenta.IterateOptions{PageSize: 40, Query: query})
+		if err != nil {
+			log.Printf("Сравнение прервано: %v", err)
+		}
+		finish(client, cfg, *sessionFile)
+		schemaErr := reportSchema(cfg.Schema, *schemaFile, *schemaUpdate)
 
-	// Логируем важные куки
-	for _, ck := range httpCookies {
-		name := ck.Name
-		if strings.Contains(name, "qrator") || name == "Utk_SessionToken" || name == "UserSessionId" {
-			val := ck.Value
-			if len(val) > 16 {
-				val = val[:16] + "..."
-			}
-			log.Printf("PW Куки: %-20s = %s", name, val)
+		if err := lenta.ExportComparisonCSV(cmp, *output); err != nil {
+			log.Fatalf("Ошибка экспорта: %v", err)
+		}
+		log.Printf("Сравнение %d товаров в %d магазинах → %s", len(cmp.Rows), len(targets), *output)
+		if schemaErr != nil {
+			log.Fatal(schemaErr)
 		}
+		return
 	}
 
-	// Извлекаем Utk_SessionToken.
-	// Он обязателен для заголовка `sessiontoken`.
+	// Товары пишутся в файл по мере обхода; формат — по -format или расширению.
+	var exporter lenta.Exporter
+	if *output != "" {
+		if exporter, err = lenta.NewExporter(*output, lenta.ExportOptions{Format: exportFormat, Raw: *rawJSON, Full: *fullCSV}); err != nil {
+			log.Fatal("Ошибка экспорта:", err)
+		}
+	}
+	exported := 0
+	storeWarned := false
 
-	sessionToken, err := client.ExtractSessionToken()
-	if err == nil && sessionToken != "" {
-		cfg.SessionToken = sessionToken
-		log.Printf("Utk_SessionToken установлен: %s...", sessionToken[:16])
-	} else {
-		log.Println("[WARN] Utk_SessionToken НЕ найден — высокая вероятность 403/401")
+	if cp == nil && *checkpointPath != "" {
+		if cp, err = lenta.NewCheckpoint(*checkpointPath, params); err != nil {
+			log.Fatal("Ошибка создания checkpoint-а:", err)
+		}
+		cp.Tree = tree.Roots
 	}
 
-	categories := []struct {
-		ID   int
-		Name string
-		Slug string
-	}{
-		{128, "Молочная продукция", "moloko-128"},
-		// {129, "Хлеб и выпечка", "hleb-vypechka-129"},
+	// История цен: запуск в базе и наблюдения цен по категориям.
+	var run *storage.Run
+	runStatus := storage.RunDone
+	if db != nil {
+		if err := db.SaveCategories(dbCtx, tree); err != nil {
+			log.Printf("Не удалось сохранить категории: %v", err)
+		}
+		if id := client.StoreID(); id != 0 {
+			if err := db.SaveStore(dbCtx, lenta.Store{ID: id, Region: client.Region()}); err != nil {
+				log.Printf("Не удалось сохранить магазин: %v", err)
+			}
+		}
+		if cp != nil && cp.RunID != 0 {
+			run, err = db.ResumeRun(dbCtx, cp.RunID)
+		} else {
+			run, err = db.StartRun(dbCtx, client.Region(), deliveryMode, client.StoreID())
+		}
+		if err != nil {
+			log.Fatal("Ошибка записи в базу:", err)
+		}
+		if cp != nil {
+			cp.RunID = run.ID
+		}
+		log.Printf("Запуск #%d → %s", run.ID, *dbPath)
 	}
 
-	var allProducts []lenta.ProductExport
+	// Товары, собранные до падения, снова идут в выгрузку. Для недообойдённых
+	// категорий они же — уже выданные ID (без повторов) и начало пачки для базы.
+	seen := make(map[int]map[int]bool)
+	batches := make(map[int][]lenta.Product)
+	if cp != nil {
+		for _, r := range cp.Restored() {
+			if exporter != nil {
+				if err := exporter.Write(&r.Product); err != nil {
+					log.Fatal("Ошибка экспорта:", err)
+				}
+			}
+			exported++
+			if cp.IsCompleted(r.CategoryID) {
+				continue
+			}
+			if seen[r.CategoryID] == nil {
+				seen[r.CategoryID] = make(map[int]bool)
+			}
+			seen[r.CategoryID][r.Product.ID] = true
+			if db != nil {
+				batches[r.CategoryID] = append(batches[r.CategoryID], r.Product)
+			}
+		}
+	}
 
 	fmt.Println("Товар | Цена | Ссылка")
 
-	// Итерация по категориям.
-	// Для каждой категории выполняется пагинация через offset/limit.
+	// Обход категорий пулом воркеров.
+	// Пагинацию, остановку и дедупликацию выполняет lenta.IterateCategory,
+	// результаты приходят постранично, и позиция в checkpoint-е сохраняется
+	// только после того, как товары страницы записаны.
 
+	var pending []int
 	for _, cat := range categories {
-		offset := 0
-		limit := 40
+		if cp == nil || !cp.IsCompleted(cat.ID) {
+			pending = append(pending, cat.ID)
+		}
+	}
+	crawlCtx, cancelCrawl := context.WithCancel(ctx)
+	defer cancelCrawl()
+	results := lenta.Crawl(crawlCtx, client, pending, lenta.CrawlOptions{
+		Workers: *workers,
+		Ordered: *ordered,
+		Options: func(categoryID int) lenta.IterateOptions {
+			opts := lenta.IterateOptions{PageSize: 40, Query: query, Seen: seen[categoryID]}
+			if cp != nil {
+				opts.StartOffset = cp.ResumeOffset(categoryID)
+			}
+			return opts
+		},
+	})
 
-		for {
-			data, err := lenta.FetchCategory(client, cat.ID, offset, limit)
-			if err != nil {
-				log.Printf("Ошибка категории %d (%s, offset %d): %v", cat.ID, cat.Name, offset, err)
-				break
+	// После ошибки экспорта или базы обход отменяется, но канал дочитывается:
+	// воркеры завершают текущие запросы, их товары уже не записываются.
+	incomplete, stop := false, false
+	for res := range results {
+		if stop {
+			incomplete = true
+			continue
+		}
+		cat := tree.Find(res.CategoryID)
+
+		if res.Done {
+			if db != nil {
+				if err := db.SaveProducts(dbCtx, run, res.CategoryID, batches[res.CategoryID]); err != nil {
+					log.Printf("Ошибка записи в базу: %v", err)
+					stop = true
+				}
+				delete(batches, res.CategoryID)
+			}
+			if res.Err != nil && !errors.Is(res.Err, lenta.ErrCanceled) {
+				log.Printf("Ошибка категории %d (%s): %v", res.CategoryID, strings.Join(cat.Path(), " / "), res.Err)
+				stop = stop || errors.Is(res.Err, lenta.ErrSchemaDrift)
+			}
+			switch {
+			case res.Err != nil || stop:
+				incomplete = true
+			case cp != nil:
+				if err := cp.Complete(res.CategoryID); err != nil {
+					log.Printf("Не удалось сохранить checkpoint: %v", err)
+				}
 			}
+			if stop {
+				runStatus = storage.RunFailed
+				cancelCrawl()
+			}
+			continue
+		}
 
-			for _, item := range data.Items {
-				link := "https://lenta.com/p/" + item.Slug
-				price := float64(item.Prices.Price) / 100
+		for _, item := range res.Products {
+			if id := client.StoreID(); id != 0 && item.StoreID != 0 && item.StoreID != id && !storeWarned {
+				log.Printf("[WARN] API вернул цены магазина %d вместо %d", item.StoreID, id)
+				storeWarned = true
+			}
 
-				allProducts = append(allProducts, lenta.ProductExport{
-					Name:  item.Name,
-					Price: price,
-					URL:   link,
-				})
+			if exporter != nil {
+				if err := exporter.Write(&item); err != nil {
+					log.Printf("Ошибка экспорта: %v", err)
+					stop = true
+					break
+				}
+			}
+			if cp != nil {
+				if err := cp.Add(res.CategoryID, &item); err != nil {
+					log.Printf("Не удалось сохранить checkpoint: %v", err)
+				}
+			}
+			if db != nil {
+				batches[res.CategoryID] = append(batches[res.CategoryID], item)
+			}
+			exported++
 
-				fmt.Printf("%s | %.2f ₽ | %s\n", item.Name, price, link)
+			fmt.Printf("%s | %.2f ₽ | %s\n", item.Name, float64(item.Prices.Price)/100, item.URL())
+		}
+		if stop {
+			incomplete = true
+			runStatus = storage.RunFailed
+			cancelCrawl()
+			continue
+		}
+		if cp != nil {
+			if err := cp.Page(res.Page); err != nil {
+				log.Printf("Не удалось сохранить checkpoint: %v", err)
 			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		log.Println("Обход прерван сигналом")
+		if runStatus == storage.RunDone {
+			runStatus = storage.RunCanceled
+		}
+	}
+	if incomplete && runStatus == storage.RunDone {
+		runStatus = storage.RunPartial
+	}
 
-			if len(data.Items) < limit {
-				break
+	// Checkpoint нужен, только если что-то не обойдено.
+	if cp != nil {
+		if incomplete {
+			if err := cp.Close(); err != nil {
+				log.Printf("Не удалось сохранить checkpoint: %v", err)
 			}
+			log.Printf("Обход не завершён — продолжить с места остановки: -resume (%s)", *checkpointPath)
+		} else if err := cp.Remove(); err != nil {
+			log.Printf("Не удалось удалить checkpoint: %v", err)
+		}
+	}
 
-			offset += limit
-			time.Sleep(2500*time.Millisecond + time.Duration(rand.Intn(3000))*time.Millisecond)
+	if db != nil {
+		if err := db.FinishRun(run, runStatus); err != nil {
+			log.Printf("Ошибка записи в базу: %v", err)
+		} else {
+			log.Printf("Запуск #%d: %s, цен записано %d", run.ID, runStatus, run.Products)
 		}
 	}
 
-	if len(allProducts) > 0 && *output != "" {
-		if err := lenta.ExportToCSV(allProducts, *output); err != nil {
+	finish(client, cfg, *sessionFile)
+	schemaErr := reportSchema(cfg.Schema, *schemaFile, *schemaUpdate)
+
+	if exporter != nil {
+		if err := exporter.Close(); err != nil {
 			log.Printf("Ошибка экспорта: %v", err)
-		} else {
-			log.Printf("Выгружено %d товаров → %s", len(allProducts), *output)
+		} else if exported > 0 {
+			log.Printf("Выгружено %d товаров → %s", exported, *output)
 		}
-	} else {
+	}
+	if exported == 0 {
 		log.Println("Товары не собраны — проверьте куки, прокси, fingerprint")
 	}
+	if schemaErr != nil {
+		log.Fatal(schemaErr)
+	}
+}
+
+// finish сохраняет сессию с обновлёнными сервером cookies для следующего запуска
+// и выводит статистику пула соединений, повторов и прокси.
+
+func finish(client *lenta.Client, cfg *lenta.Config, sessionFile string) {
+	if sessionFile != "" {
+		if err := lenta.SaveSession(sessionFile, client.Session()); err != nil {
+			log.Printf("Не удалось сохранить сессию: %v", err)
+		}
+	}
+
+	st := client.PoolStats()
+	log.Printf("Пул соединений: открыто %d, handshake %d, переиспользовано %d, закрыто %d",
+		st.Open, st.Dials, st.Reuses, st.Evictions)
+	rs := client.RetryStats()
+	log.Printf("Повторы: %d (исчерпано попыток: %d) %v", rs.Retries, rs.Exhausted, rs.ByKind)
+
+	if cfg.ProxyPool != nil {
+		for _, ps := range cfg.ProxyPool.Stats() {
+			log.Printf("Прокси %s: успешно %d, ошибок %d, здоров %v", ps.URL, ps.Successes, ps.Failures, ps.Healthy)
+		}
+	}
+}
+
+// reportSchema выводит отчёт о дрейфе схемы и при update сохраняет новый baseline.
+// В строгом режиме возвращает ошибку, если расхождения есть.
+
+func reportSchema(tracker *lenta.SchemaTracker, path string, update bool) error {
+	if tracker == nil {
+		return nil
+	}
+
+	drifts := tracker.Report()
+	for _, d := range drifts {
+		log.Printf("[SCHEMA] %s", d)
+	}
+	if len(drifts) == 0 {
+		log.Println("Схема ответов API не изменилась")
+	}
+
+	if update && path != "" {
+		if err := lenta.SaveSchemaBaseline(path, tracker.Observed()); err != nil {
+			log.Printf("Не удалось сохранить baseline схемы: %v", err)
+		} else {
+			log.Printf("Baseline схемы обновлён → %s", path)
+		}
+		return nil
+	}
+
+	if tracker.Strict && len(drifts) > 0 {
+		return fmt.Errorf("%w: расхождений %d", lenta.ErrSchemaDrift, len(drifts))
+	}
+	return nil
+}
+
+// buildQuery собирает CatalogQuery из флагов CLI.
+// Цена в -price задаётся в рублях, API ждёт копейки.
+
+func buildQuery(sortName, price, weight string, filters, facets []string) (*lenta.CatalogQuery, error) {
+	mode, err := lenta.ParseSort(sortName)
+	if err != nil {
+		return nil, err
+	}
+	q := lenta.NewCatalogQuery().SortBy(mode)
+
+	if price != "" {
+		from, to, err := lenta.ParseRange(price)
+		if err != nil {
+			return nil, fmt.Errorf("-price: %w", err)
+		}
+		// Целые копейки: 49,9 × 100 в float — 4989.999…
+		for _, v := range []*float64{from, to} {
+			if v != nil {
+				*v = math.Round(*v * 100)
+			}
+		}
+		q.Between(lenta.FilterPrice, from, to)
+	}
+	if weight != "" {
+		from, to, err := lenta.ParseRange(weight)
+		if err != nil {
+			return nil, fmt.Errorf("-weight: %w", err)
+		}
+		q.Between(lenta.FilterWeight, from, to)
+	}
+
+	for _, f := range filters {
+		key, values, err := lenta.ParseFilter(f)
+		if err != nil {
+			return nil, fmt.Errorf("-filter: %w", err)
+		}
+		q.Checkbox(key, values...)
+	}
+	for _, f := range facets {
+		key, values, err := lenta.ParseFilter(f)
+		if err != nil {
+			return nil, fmt.Errorf("-facet: %w", err)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("-facet %q: нужны значения key=value", f)
+		}
+		q.Multicheckbox(key, values...)
+	}
+	return q, nil
+}
+
+// resolveStore находит ID магазина по ID или части адреса.
+// Если под адрес подходит несколько магазинов, возвращает ошибку со списком.
+
+func resolveStore(ctx context.Context, client *lenta.Client, ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+
+	stores, err := lenta.ListStores(ctx, client, "")
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения списка магазинов: %w", err)
+	}
+	found := lenta.FindStores(stores, ref)
+	switch len(found) {
+	case 0:
+		return 0, fmt.Errorf("магазин %q не найден в регионе %s", ref, client.Region())
+	case 1:
+		return found[0].ID, nil
+	default:
+		printStores(found)
+		return 0, fmt.Errorf("под %q подходит %d магазинов — уточните адрес или укажите ID", ref, len(found))
+	}
+}
+
+func printStores(stores []lenta.Store) {
+	fmt.Println("ID | Адрес | Самовывоз | Доставка")
+	for _, s := range stores {
+		fmt.Printf("%d | %s | %v | %v\n", s.ID, s.Address, s.IsPickup, s.IsCourier)
+	}
+}
+
+// listFlag — повторяемый строковый флаг.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, "; ") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
 }
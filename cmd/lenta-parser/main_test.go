@@ -0,0 +1,25 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestBuildQueryPriceKopecks — рубли из -price уходят в API целыми копейками.
+func TestBuildQueryPriceKopecks(t *testing.T) {
+	tests := map[string]string{
+		"49,9..":     `[{"key":"price","min":4990}]`,
+		"0.29..1.15": `[{"key":"price","min":29,"max":115}]`,
+		"..199.99":   `[{"key":"price","max":19999}]`,
+	}
+	for price, want := range tests {
+		q, err := buildQuery("", price, "", nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", price, err)
+		}
+		got, _ := json.Marshal(q.Ranges)
+		if string(got) != want {
+			t.Errorf("-price %s: %s, ожидалось %s", price, got, want)
+		}
+	}
+}
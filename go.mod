@@ -6,18 +6,27 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/playwright-community/playwright-go v0.5200.1
 	github.com/refraction-networking/utls v1.8.2
+	modernc.org/sqlite v1.40.1
 )
 
 require (
 	github.com/deckarep/golang-set/v2 v2.7.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/go-jose/go-jose/v3 v3.0.4 // indirect
 	github.com/go-stack/stack v1.8.1 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
 	golang.org/x/text v0.34.0 // indirect
+	modernc.org/libc v1.66.10 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
 )
 
 require (
-	github.com/andybalholm/brotli v1.2.0 // indirect
-	github.com/klauspost/compress v1.18.4 // indirect
+	github.com/andybalholm/brotli v1.2.0
+	github.com/klauspost/compress v1.18.4
 	golang.org/x/crypto v0.48.0 // indirect
 	golang.org/x/net v0.49.0
 	golang.org/x/sys v0.41.0 // indirect
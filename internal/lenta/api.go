@@ -2,45 +2,46 @@ package lenta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 )
 
-// FetchCategory выполняет POST-запрос к catalog API.
+// FetchCategory — FetchCategoryContext с context.Background().
+
+func FetchCategory(client *Client, categoryID int, offset int, limit int) (*CatalogItemsResponse, error) {
+	return FetchCategoryContext(context.Background(), client, categoryID, offset, limit)
+}
+
+// FetchCategoryContext — FetchCatalogContext без фильтров с сортировкой popular.
+
+func FetchCategoryContext(ctx context.Context, client *Client, categoryID int, offset int, limit int) (*CatalogItemsResponse, error) {
+	return FetchCatalogContext(ctx, client, categoryID, nil, offset, limit)
+}
+
+// FetchCatalogContext выполняет POST-запрос к catalog API.
+// Фильтры и сортировка берутся из query (nil — без фильтров, popular).
 //
 // Требования:
 // - Валидный sessiontoken
 // - Anti-bot cookies
 // - Корректный TLS fingerprint (uTLS)
+// Контекст ограничивает dial, uTLS handshake и HTTP/2 round trip;
+// при отмене возвращается ошибка, для которой errors.Is(err, ErrCanceled).
+// Ошибки API возвращаются как *APIError (см. ErrorKind).
 // Возвращает десериализованный JSON ответ.
 
-func FetchCategory(client *Client, categoryID int, offset int, limit int) (*CatalogItemsResponse, error) {
+func FetchCatalogContext(ctx context.Context, client *Client, categoryID int, query *CatalogQuery, offset int, limit int) (*CatalogItemsResponse, error) {
 	urlStr := client.BaseURL() + "/api-gateway/v1/catalog/items"
 
-	payload := map[string]interface{}{
-		"categoryId": categoryID,
-		"filters": map[string]interface{}{
-			"range":         []interface{}{},
-			"checkbox":      []interface{}{},
-			"multicheckbox": []interface{}{},
-		},
-		"limit":  limit,
-		"offset": offset,
-		"sort": map[string]string{
-			"type":  "popular",
-			"order": "desc",
-		},
-	}
-
-	bodyBytes, err := json.Marshal(payload)
+	bodyBytes, err := json.Marshal(query.payload(categoryID, offset, limit))
 	if err != nil {
 		return nil, err
 	}
 
-	req, err := http.NewRequest("POST", urlStr, bytes.NewBuffer(bodyBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", urlStr, bytes.NewBuffer(bodyBytes))
 	if err != nil {
 		return nil, err
 	}
@@ -49,22 +50,31 @@ func FetchCategory(client *Client, categoryID int, offset int, limit int) (*Cata
 
 	log.Printf("POST к %s с телом: %s", urlStr, string(bodyBytes))
 
-	resp, err := client.Do(req)
+	resp, err := client.DoContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, canceled(ctx, err)
+		}
+		return nil, newTransportError(req, err)
+	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка API: %s. Тело: %s", resp.Status, string(body))
+		return nil, newStatusError(resp, body)
 	}
 
 	var data CatalogItemsResponse
-	if err := json.Unmarshal(body, &data); err != nil {
+	if err := client.checkSchema(resp, body, data); err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, newDecodeError(resp, body, err)
+	}
 
 	return &data, nil
 }
@@ -0,0 +1,249 @@
+package lenta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CategoryNode — узел дерева категорий каталога.
+
+type CategoryNode struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name,omitempty"`
+	Slug     string          `json:"slug,omitempty"`
+	Parent   *CategoryNode   `json:"-"`
+	Children []*CategoryNode `json:"children,omitempty"`
+}
+
+// IsLeaf — у категории нет подкатегорий (в ней лежат товары).
+func (n *CategoryNode) IsLeaf() bool {
+	return len(n.Children) == 0
+}
+
+// Path возвращает имена категорий от корня до n.
+func (n *CategoryNode) Path() []string {
+	var path []string
+	for cur := n; cur != nil; cur = cur.Parent {
+		name := cur.Name
+		if name == "" {
+			name = strconv.Itoa(cur.ID)
+		}
+		path = append([]string{name}, path...)
+	}
+	return path
+}
+
+// Leaves возвращает листовые категории поддерева n (включая n, если он лист).
+func (n *CategoryNode) Leaves() []*CategoryNode {
+	if n.IsLeaf() {
+		return []*CategoryNode{n}
+	}
+	var out []*CategoryNode
+	for _, ch := range n.Children {
+		out = append(out, ch.Leaves()...)
+	}
+	return out
+}
+
+// CategoryTree — дерево категорий с индексом по ID и slug.
+
+type CategoryTree struct {
+	Roots  []*CategoryNode
+	byID   map[int]*CategoryNode
+	bySlug map[string]*CategoryNode
+}
+
+func newCategoryTree() *CategoryTree {
+	return &CategoryTree{byID: make(map[int]*CategoryNode), bySlug: make(map[string]*CategoryNode)}
+}
+
+// NewCategoryTree собирает дерево из корней, например прочитанных из JSON:
+// восстанавливает ссылки на родителей и индексы.
+func NewCategoryTree(roots []*CategoryNode) *CategoryTree {
+	t := newCategoryTree()
+	var link func(n, parent *CategoryNode)
+	link = func(n, parent *CategoryNode) {
+		n.Parent = parent
+		t.add(n)
+		for _, ch := range n.Children {
+			link(ch, n)
+		}
+	}
+	for _, r := range roots {
+		link(r, nil)
+		t.Roots = append(t.Roots, r)
+	}
+	return t
+}
+
+func (t *CategoryTree) add(n *CategoryNode) {
+	t.byID[n.ID] = n
+	if n.Slug != "" {
+		t.bySlug[n.Slug] = n
+	}
+}
+
+// Find ищет категорию по ID.
+func (t *CategoryTree) Find(id int) *CategoryNode {
+	return t.byID[id]
+}
+
+// Lookup ищет категорию по ID или slug (например "128" или "moloko-128").
+func (t *CategoryTree) Lookup(ref string) *CategoryNode {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.Atoi(ref); err == nil {
+		return t.byID[id]
+	}
+	return t.bySlug[ref]
+}
+
+// Leaves возвращает все листовые категории дерева.
+func (t *CategoryTree) Leaves() []*CategoryNode {
+	var out []*CategoryNode
+	for _, r := range t.Roots {
+		out = append(out, r.Leaves()...)
+	}
+	return out
+}
+
+// Len — число категорий в дереве.
+func (t *CategoryTree) Len() int {
+	return len(t.byID)
+}
+
+// DiscoverOptions — параметры обхода дерева категорий.
+
+type DiscoverOptions struct {
+	MaxDepth int // 0 — без ограничения
+}
+
+type categoriesResponse struct {
+	Categories []Category `json:"categories"`
+}
+
+// FetchRootCategories возвращает категории верхнего уровня каталога.
+
+func FetchRootCategories(ctx context.Context, client *Client) ([]Category, error) {
+	urlStr := client.BaseURL() + "/api-gateway/v1/catalog/categories"
+
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.DoContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, canceled(ctx, err)
+		}
+		return nil, newTransportError(req, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp, body)
+	}
+
+	var data categoriesResponse
+	if err := client.checkSchema(resp, body, data); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, newDecodeError(resp, body, err)
+	}
+
+	// Эндпоинт может отдать и вложенные категории — их найдёт обход дерева.
+	var roots []Category
+	for _, c := range data.Categories {
+		if c.ParentID == 0 {
+			roots = append(roots, c)
+		}
+	}
+	return roots, nil
+}
+
+// DiscoverCategories строит дерево категорий, рекурсивно спускаясь от rootIDs.
+// Пустой rootIDs — весь каталог: корнями становятся категории верхнего уровня.
+//
+// Эндпоинта всего дерева у catalog API нет, поэтому дочерние категории
+// берутся из поля categories ответа catalog/items (запрашивается одна позиция).
+// Категории без детей (hasChildren=false) не запрашиваются повторно.
+// Уже встреченные ID пропускаются — циклов и дублей в дереве нет.
+
+func DiscoverCategories(ctx context.Context, client *Client, rootIDs []int, opts DiscoverOptions) (*CategoryTree, error) {
+	tree := newCategoryTree()
+
+	roots := make([]Category, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		roots = append(roots, Category{ID: id})
+	}
+	if len(roots) == 0 {
+		var err error
+		if roots, err = FetchRootCategories(ctx, client); err != nil {
+			return tree, fmt.Errorf("корневые категории: %w", err)
+		}
+		if len(roots) == 0 {
+			return tree, fmt.Errorf("корневые категории: API вернул пустой список")
+		}
+	}
+
+	for _, c := range roots {
+		if tree.Find(c.ID) != nil {
+			continue
+		}
+		root := &CategoryNode{ID: c.ID, Name: c.Name, Slug: c.Slug}
+		tree.add(root)
+		tree.Roots = append(tree.Roots, root)
+
+		if err := discoverChildren(ctx, client, tree, root, 1, opts); err != nil {
+			return tree, err
+		}
+	}
+	return tree, nil
+}
+
+func discoverChildren(ctx context.Context, client *Client, tree *CategoryTree, node *CategoryNode, depth int, opts DiscoverOptions) error {
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return nil
+	}
+
+	data, err := FetchCategoryContext(ctx, client, node.ID, 0, 1)
+	if err != nil {
+		return fmt.Errorf("категория %d: %w", node.ID, err)
+	}
+
+	for _, c := range data.Categories {
+		if c.ID == node.ID {
+			// Ответ описывает саму категорию — забираем имя и slug.
+			node.Name, node.Slug = c.Name, c.Slug
+			tree.add(node)
+			continue
+		}
+		if tree.Find(c.ID) != nil {
+			continue
+		}
+
+		child := &CategoryNode{ID: c.ID, Name: c.Name, Slug: c.Slug, Parent: node}
+		node.Children = append(node.Children, child)
+		tree.add(child)
+		log.Printf("Категория: %s", strings.Join(child.Path(), " / "))
+
+		if c.HasChildren {
+			if err := discoverChildren(ctx, client, tree, child, depth+1, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package lenta
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestDiscoverCategoriesCatalog — без rootIDs дерево строится от категорий
+// верхнего уровня всего каталога.
+func TestDiscoverCategoriesCatalog(t *testing.T) {
+	children := map[int][]Category{
+		1:  {{ID: 1, Name: "Молочное", Slug: "moloko-1"}, {ID: 11, Name: "Молоко"}, {ID: 12, Name: "Сыры", HasChildren: true}},
+		12: {{ID: 121, Name: "Твёрдые"}, {ID: 122, Name: "Мягкие"}},
+		2:  {{ID: 21, Name: "Хлеб"}},
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(categoriesResponse{Categories: []Category{
+				{ID: 1, Name: "Молочное", HasChildren: true},
+				{ID: 11, Name: "Молоко", ParentID: 1},
+				{ID: 2, Name: "Хлеб и выпечка", HasChildren: true},
+			}})
+			return
+		}
+		var body struct {
+			CategoryID int `json:"categoryId"`
+		}
+		json.NewDecoder(req.Body).Decode(&body)
+		json.NewEncoder(w).Encode(CatalogItemsResponse{Items: []Product{}, Categories: children[body.CategoryID]})
+	})
+
+	tree, err := DiscoverCategories(context.Background(), client, nil, DiscoverOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.Roots) != 2 {
+		t.Fatalf("корней %d, ожидалось 2", len(tree.Roots))
+	}
+	var leaves []int
+	for _, n := range tree.Leaves() {
+		leaves = append(leaves, n.ID)
+	}
+	sort.Ints(leaves)
+	if want := []int{11, 21, 121, 122}; !reflect.DeepEqual(leaves, want) {
+		t.Errorf("листья %v, ожидались %v", leaves, want)
+	}
+	if n := tree.Lookup("moloko-1"); n == nil || n.ID != 1 {
+		t.Errorf("Lookup(moloko-1) = %v", n)
+	}
+}
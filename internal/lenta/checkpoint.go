@@ -0,0 +1,271 @@
+package lenta
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint — состояние долгого обхода для продолжения после падения.
+//
+// Сам файл (JSON) хранит позицию: завершённые категории, offset, с которого
+// продолжать каждую начатую (при параллельном обходе их несколько),
+// и число собранных товаров Items.
+// Товары дописываются построчно в соседний файл <path>.items.jsonl —
+// перезаписывать их целиком после каждой страницы было бы слишком дорого.
+// При загрузке всё, что записано в .items.jsonl после последнего сохранения
+// позиции, отбрасывается: товары не дублируются и не теряются.
+//
+// Методы можно вызывать из нескольких горутин: воркеры Crawl читают
+// ResumeOffset, пока основной цикл записывает страницы.
+
+type Checkpoint struct {
+	SavedAt   time.Time       `json:"savedAt"`
+	Params    string          `json:"params"`          // параметры обхода; продолжать можно только с теми же
+	RunID     int64           `json:"runId,omitempty"` // запуск в базе истории цен
+	Tree      []*CategoryNode `json:"tree,omitempty"`  // дерево категорий, чтобы не строить его заново
+	Completed []int           `json:"completed"`
+	Offsets   map[int]int     `json:"offsets,omitempty"` // начатые категории → offset продолжения
+	Items     int             `json:"items"`
+
+	mu        sync.Mutex
+	committed int // Items на момент последнего Page или Complete
+	path      string
+	f         *os.File
+	w         *bufio.Writer
+	completed map[int]bool
+	restored  []CheckpointItem
+}
+
+// CheckpointItem — товар, собранный до падения, и его категория.
+
+type CheckpointItem struct {
+	CategoryID int
+	Product    Product
+}
+
+// checkpointLine — строка файла .items.jsonl.
+type checkpointLine struct {
+	Category int             `json:"category"`
+	Item     json.RawMessage `json:"item"`
+}
+
+func itemsPath(path string) string {
+	return path + ".items.jsonl"
+}
+
+// NewCheckpoint начинает новый обход: прежние файлы checkpoint-а перезаписываются.
+
+func NewCheckpoint(path, params string) (*Checkpoint, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(itemsPath(path))
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{Params: params, path: path, f: f, w: bufio.NewWriter(f), completed: make(map[int]bool)}
+	if err := c.save(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadCheckpoint открывает checkpoint прерванного обхода.
+// Отсутствие файла — ошибка, для которой errors.Is(err, os.ErrNotExist).
+
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Checkpoint{path: path, completed: make(map[int]bool)}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %w", path, err)
+	}
+	for _, id := range c.Completed {
+		c.completed[id] = true
+	}
+	c.committed = c.Items
+
+	f, err := os.OpenFile(itemsPath(path), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	size, err := c.readItems(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("checkpoint %s: %w", itemsPath(path), err)
+	}
+	// Отрезаем товары, записанные после последнего сохранения позиции.
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	c.f, c.w = f, bufio.NewWriter(f)
+	return c, nil
+}
+
+// readItems читает первые Items строк и возвращает их длину в байтах.
+func (c *Checkpoint) readItems(f *os.File) (int64, error) {
+	r := bufio.NewReader(f)
+	var size int64
+	for len(c.restored) < c.Items {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return 0, fmt.Errorf("записано %d товаров из %d: %w", len(c.restored), c.Items, err)
+		}
+		var cl checkpointLine
+		if err := json.Unmarshal(line, &cl); err != nil {
+			return 0, fmt.Errorf("строка %d: %w", len(c.restored)+1, err)
+		}
+		var p Product
+		if err := json.Unmarshal(cl.Item, &p); err != nil {
+			return 0, fmt.Errorf("строка %d: %w", len(c.restored)+1, err)
+		}
+		c.restored = append(c.restored, CheckpointItem{CategoryID: cl.Category, Product: p})
+		size += int64(len(line))
+	}
+	return size, nil
+}
+
+// Restored возвращает товары, собранные до падения, в исходном порядке.
+func (c *Checkpoint) Restored() []CheckpointItem {
+	return c.restored
+}
+
+// IsCompleted — категория обойдена полностью.
+func (c *Checkpoint) IsCompleted(categoryID int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.completed[categoryID]
+}
+
+// ResumeOffset — с какого offset продолжать категорию (0 — с начала).
+func (c *Checkpoint) ResumeOffset(categoryID int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Offsets[categoryID]
+}
+
+// Add дописывает товар в .items.jsonl. Позиция сохраняется в Page и Complete.
+func (c *Checkpoint) Add(categoryID int, p *Product) error {
+	item := p.Raw
+	if len(item) == 0 {
+		var err error
+		if item, err = json.Marshal(p); err != nil {
+			return err
+		}
+	}
+	line, err := json.Marshal(checkpointLine{Category: categoryID, Item: item})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.Write(line)
+	c.w.WriteByte('\n')
+	c.Items++
+	return nil
+}
+
+// Page сохраняет позицию после обработки страницы info:
+// все её товары уже переданы в Add, продолжать с NextOffset.
+
+func (c *Checkpoint) Page(info PageInfo) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Offsets == nil {
+		c.Offsets = make(map[int]int)
+	}
+	c.Offsets[info.CategoryID] = info.NextOffset
+	return c.commit()
+}
+
+// Complete отмечает категорию обойдённой полностью.
+func (c *Checkpoint) Complete(categoryID int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.completed[categoryID] {
+		c.completed[categoryID] = true
+		c.Completed = append(c.Completed, categoryID)
+	}
+	delete(c.Offsets, categoryID)
+	return c.commit()
+}
+
+// commit сохраняет позицию вместе со всеми добавленными товарами.
+func (c *Checkpoint) commit() error {
+	if err := c.save(); err != nil {
+		return err
+	}
+	c.committed = c.Items
+	return nil
+}
+
+// save сбрасывает товары на диск и атомарно перезаписывает позицию.
+// Порядок важен: позиция не должна ссылаться на товары, которых нет на диске.
+// Вызывается под c.mu (кроме NewCheckpoint, где checkpoint ещё не виден другим).
+
+func (c *Checkpoint) save() error {
+	if c.w != nil {
+		if err := c.w.Flush(); err != nil {
+			return err
+		}
+		if err := c.f.Sync(); err != nil {
+			return err
+		}
+	}
+
+	c.SavedAt = time.Now()
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// Close сохраняет позицию и закрывает файл товаров; checkpoint остаётся на диске.
+// Товары, добавленные после последнего Page или Complete, не сохраняются:
+// их страница при продолжении будет запрошена снова целиком. Иначе она
+// состояла бы из уже выданных товаров и остановила бы пагинацию категории.
+func (c *Checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Items = c.committed
+	err := c.save()
+	if cerr := c.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Remove удаляет файлы checkpoint-а после успешного завершения обхода.
+func (c *Checkpoint) Remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.f.Close()
+	err := os.Remove(c.path)
+	if rerr := os.Remove(itemsPath(c.path)); err == nil && !errors.Is(rerr, os.ErrNotExist) {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package lenta
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+// TestCheckpointResume — после падения восстанавливаются только товары
+// до последнего сохранения позиции, а продолжение дописывает новые следом.
+func TestCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.json")
+	cp, err := NewCheckpoint(path, "cat=1,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := 1; id <= 3; id++ {
+		if err := cp.Add(1, &Product{ID: id, Name: "товар"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cp.Complete(1); err != nil {
+		t.Fatal(err)
+	}
+	for id := 4; id <= 5; id++ {
+		cp.Add(2, &Product{ID: id})
+	}
+	if err := cp.Page(PageInfo{CategoryID: 2, Offset: 0, NextOffset: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// Товары следующей страницы дошли до диска, а позиция — нет: так выглядит падение.
+	cp.Add(2, &Product{ID: 6})
+	cp.w.Flush()
+	cp.f.Close()
+
+	cp, err = LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cp.Params != "cat=1,2" || !cp.IsCompleted(1) || cp.IsCompleted(2) {
+		t.Errorf("позиция восстановлена неверно: %+v", cp)
+	}
+	if off := cp.ResumeOffset(2); off != 2 {
+		t.Errorf("ResumeOffset(2) = %d, ожидался 2", off)
+	}
+	var ids []int
+	for _, it := range cp.Restored() {
+		ids = append(ids, it.Product.ID)
+	}
+	if len(ids) != 5 || ids[0] != 1 || ids[4] != 5 {
+		t.Fatalf("восстановлены товары %v, ожидались 1..5", ids)
+	}
+
+	// Продолжение: хвост отброшенной страницы не должен остаться в файле.
+	cp.Add(2, &Product{ID: 7})
+	if err := cp.Complete(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cp, err = LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Remove()
+	restored := cp.Restored()
+	if len(restored) != 6 || restored[5].Product.ID != 7 || restored[5].CategoryID != 2 {
+		t.Errorf("после продолжения восстановлено %+v", restored)
+	}
+	if cp.ResumeOffset(2) != 0 {
+		t.Errorf("у завершённой категории остался offset %d", cp.ResumeOffset(2))
+	}
+}
+
+// TestCheckpointStopMidPage — обход остановлен посреди страницы (например,
+// после ошибки экспорта). При продолжении страница запрашивается снова,
+// и последующие страницы категории не теряются.
+func TestCheckpointStopMidPage(t *testing.T) {
+	const total = 95
+	client := newTestClient(t, catalogHandler(total))
+	path := filepath.Join(t.TempDir(), "crawl.json")
+	cp, err := NewCheckpoint(path, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	got := make(map[int]bool)
+	pages := 0
+	for res := range Crawl(ctx, client, []int{1}, CrawlOptions{}) {
+		if res.Done || ctx.Err() != nil {
+			continue
+		}
+		if pages++; pages == 2 {
+			// Вторая страница записана наполовину, позиция не сохранена.
+			for i := range res.Products[:10] {
+				cp.Add(1, &res.Products[i])
+			}
+			cancel()
+			continue
+		}
+		for i := range res.Products {
+			cp.Add(1, &res.Products[i])
+			got[res.Products[i].ID] = true
+		}
+		if err := cp.Page(res.Page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cp.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	cp, err = LoadCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.Remove()
+	seen := make(map[int]bool)
+	for _, r := range cp.Restored() {
+		seen[r.Product.ID] = true
+	}
+	if len(seen) != 40 {
+		t.Fatalf("восстановлено %d товаров, ожидалась первая страница (40)", len(seen))
+	}
+
+	results := Crawl(context.Background(), client, []int{1}, CrawlOptions{
+		Options: func(categoryID int) IterateOptions {
+			return IterateOptions{PageSize: 40, StartOffset: cp.ResumeOffset(categoryID), Seen: seen}
+		},
+	})
+	for res := range results {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		for _, p := range res.Products {
+			got[p.ID] = true
+		}
+	}
+	if len(got) != total {
+		t.Errorf("после продолжения собрано %d товаров, ожидалось %d", len(got), total)
+	}
+}
@@ -2,53 +2,92 @@ package lenta
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
-	"net/http/cookiejar"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
+	"sync"
 	"time"
-
-	utls "github.com/refraction-networking/utls"
-	"golang.org/x/net/http2"
 )
 
 const (
 	baseURL   = "https://lenta.com"
-	userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/143.0.0.0 Safari/537.36 OPR/127.0.0.0"
 	clientVer = "angular_web_0.0.2"
 )
 
 type Client struct {
-	inner *http.Client
-	cfg   *Config
+	inner   *http.Client
+	cfg     *Config
+	profile *BrowserProfile
+	pool    *connPool
+	jar     *sessionJar
+	retry   *retryState
+	limiter *rateLimiter
+
+	refresher sessionRefresher
+
+	proxyMu  sync.Mutex
+	proxyURL *url.URL // единственный прокси (Config.ProxyURL)
+	pinned   *url.URL // прокси из пула, закреплённый за сессией
 }
 
 // NewClient создаёт HTTP-клиент с:
-// - uTLS Chrome fingerprint
-// - HTTP/2
+// - uTLS fingerprint из профиля браузера (Config.Profile, по умолчанию chrome-131-win)
+// - HTTP/2 с пулом переиспользуемых соединений и порядком заголовков профиля
 // - CookieJar
 // Без uTLS сайт возвращает 403 из-за TLS fingerprint mismatch.
 
 func NewClient(cfg *Config) (*Client, error) {
-	c := &Client{cfg: cfg}
+	profile := cfg.Profile
+	if profile == nil {
+		profile = defaultProfile()
+	}
+	c := &Client{
+		cfg:     cfg,
+		profile: profile,
+		pool:    newConnPool(cfg.IdleConnTimeout, profile.HelloID, profile.HeaderOrder),
+		retry:   newRetryState(cfg.Retry),
+		limiter: newRateLimiter(cfg.RateLimit),
+	}
 
 	// Создаём CookieJar — критично для qrator_jsid и сессионных куки
-	jar, err := cookiejar.New(nil)
+	jar, err := newSessionJar()
 	if err != nil {
 		return nil, fmt.Errorf("не удалось создать CookieJar: %w", err)
 	}
+	c.jar = jar
+
+	if cfg.ProxyPool != nil {
+		log.Printf("Используется пул из %d прокси, стратегия %s (uTLS %s)", len(cfg.ProxyPool.Stats()), cfg.ProxyPool.Strategy, profile.Name)
+		c.inner = &http.Client{
+			Transport:     &rotatingUTLSTransport{client: c, pool: cfg.ProxyPool},
+			Jar:           jar,
+			Timeout:       30 * time.Second,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+		}
+		return c, nil
+	}
 
 	if cfg.ProxyURL != "" {
 		proxyURL, err := url.Parse(cfg.ProxyURL)
 		if err != nil {
 			return nil, fmt.Errorf("неверный URL прокси: %w", err)
 		}
-		log.Printf("Используется прокси: %s (uTLS Chrome fingerprint)", proxyURL.Host)
+		if err := validateProxyURL(proxyURL); err != nil {
+			return nil, err
+		}
+		log.Printf("Используется прокси: %s (uTLS %s)", proxyURL.Host, profile.Name)
+		c.proxyURL = proxyURL
 		c.inner = &http.Client{
 			Transport:     &proxyUTLSTransport{client: c, proxyURL: proxyURL},
 			Jar:           jar,
@@ -58,7 +97,7 @@ func NewClient(cfg *Config) (*Client, error) {
 		return c, nil
 	}
 
-	log.Printf("Прямое подключение (uTLS Chrome fingerprint)")
+	log.Printf("Прямое подключение (uTLS %s)", profile.Name)
 	c.inner = &http.Client{
 		Transport:     &directUTLSTransport{client: c},
 		Jar:           jar,
@@ -69,55 +108,24 @@ func NewClient(cfg *Config) (*Client, error) {
 }
 
 // directUTLSTransport реализует прямое соединение:
-// TCP → uTLS handshake (Chrome fingerprint) → HTTP/2.
-// Эмулирует реальный браузер.
+// TCP → uTLS handshake (fingerprint профиля) → HTTP/2.
+// Соединения берутся из пула клиента и переиспользуются между запросами.
 
 type directUTLSTransport struct {
 	client *Client
 }
 
 func (t *directUTLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	ctx := req.Context()
-	targetHost := req.URL.Host
-	if _, _, err := net.SplitHostPort(targetHost); err != nil {
-		targetHost = net.JoinHostPort(req.URL.Hostname(), "443")
-	}
-
-	conn, err := (&net.Dialer{Timeout: 15 * time.Second}).DialContext(ctx, "tcp", targetHost)
-	if err != nil {
-		return nil, err
-	}
-
-	hostname := req.URL.Hostname()
-	uConn := utls.UClient(conn, &utls.Config{ServerName: hostname}, utls.HelloChrome_131)
-	if d, ok := ctx.Deadline(); ok {
-		conn.SetDeadline(d)
-	}
-	if err := uConn.Handshake(); err != nil {
-		conn.Close()
-		return nil, err
-	}
-
-	tr := &http2.Transport{}
-	cc, err := tr.NewClientConn(uConn)
-	if err != nil {
-		uConn.Close()
-		return nil, err
-	}
-
-	reqToSend := req.Clone(ctx)
+	return t.client.pool.roundTrip(req, "direct", t.dial)
+}
 
-	httpResp, err := cc.RoundTrip(reqToSend)
-	if err != nil {
-		cc.Close()
-		return nil, err
-	}
-	httpResp.Body = &closeWrapper{ReadCloser: httpResp.Body, onClose: func() { _ = cc.Close() }}
-	return httpResp, nil
+func (t *directUTLSTransport) dial(ctx context.Context, addr string) (net.Conn, error) {
+	return (&net.Dialer{Timeout: 15 * time.Second}).DialContext(ctx, "tcp", addr)
 }
 
-// proxyUTLSTransport реализует CONNECT через HTTP proxy,
+// proxyUTLSTransport открывает туннель через прокси (HTTP CONNECT или SOCKS5),
 // затем выполняет uTLS handshake поверх туннеля.
+// Туннели пулятся отдельно для каждого прокси.
 
 type proxyUTLSTransport struct {
 	client   *Client
@@ -125,13 +133,47 @@ type proxyUTLSTransport struct {
 }
 
 func (t *proxyUTLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	ctx := req.Context()
-	targetHost := req.URL.Host
-	if _, _, err := net.SplitHostPort(targetHost); err != nil {
-		targetHost = net.JoinHostPort(req.URL.Hostname(), "443")
+	return t.client.pool.roundTrip(req, t.proxyURL.String(), t.dial)
+}
+
+func (t *proxyUTLSTransport) dial(ctx context.Context, targetHost string) (net.Conn, error) {
+	return dialViaProxy(ctx, t.proxyURL, targetHost)
+}
+
+// dialViaProxy открывает туннель до targetHost через прокси:
+// HTTP CONNECT для http/https (https — через TLS до прокси) или SOCKS5 для socks5/socks5h.
+
+func dialViaProxy(ctx context.Context, proxyURL *url.URL, targetHost string) (net.Conn, error) {
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		return dialSOCKS5(ctx, proxyURL, targetHost)
+	default:
+		return dialHTTPConnect(ctx, proxyURL, targetHost)
 	}
+}
+
+// validateProxyURL проверяет, что схема прокси поддерживается.
+func validateProxyURL(u *url.URL) error {
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("неподдерживаемая схема прокси %q (http, https, socks5, socks5h)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("в URL прокси не указан хост")
+	}
+	return nil
+}
 
-	proxyConn, err := (&net.Dialer{Timeout: 15 * time.Second}).DialContext(ctx, "tcp", t.proxyURL.Host)
+// proxyRootCAs — корневые сертификаты для https-прокси; nil — системные.
+var proxyRootCAs *x509.CertPool
+
+// dialHTTPConnect открывает туннель CONNECT до targetHost через HTTP proxy.
+// С https-прокси соединение до прокси шифруется: логин и CONNECT
+// не уходят открытым текстом.
+
+func dialHTTPConnect(ctx context.Context, proxyURL *url.URL, targetHost string) (net.Conn, error) {
+	proxyConn, err := (&net.Dialer{Timeout: 15 * time.Second}).DialContext(ctx, "tcp", proxyURL.Host)
 	if err != nil {
 		return nil, err
 	}
@@ -141,10 +183,22 @@ func (t *proxyUTLSTransport) RoundTrip(req *http.Request) (*http.Response, error
 		}
 	}()
 
+	if d, ok := ctx.Deadline(); ok {
+		proxyConn.SetDeadline(d)
+	}
+
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(proxyConn, &tls.Config{ServerName: proxyURL.Hostname(), RootCAs: proxyRootCAs})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("TLS до прокси %s: %w", proxyURL.Host, err)
+		}
+		proxyConn = tlsConn
+	}
+
 	proxyAuth := ""
-	if t.proxyURL.User != nil {
-		u := t.proxyURL.User.Username()
-		p, _ := t.proxyURL.User.Password()
+	if proxyURL.User != nil {
+		u := proxyURL.User.Username()
+		p, _ := proxyURL.User.Password()
 		auth := u + ":" + p
 		proxyAuth = "Proxy-Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(auth)) + "\r\n"
 	}
@@ -165,49 +219,45 @@ func (t *proxyUTLSTransport) RoundTrip(req *http.Request) (*http.Response, error
 		return nil, fmt.Errorf("proxy CONNECT failed: %d %s", resp.StatusCode, resp.Status)
 	}
 
-	peekConn := &peekConn{Conn: proxyConn, peek: br}
-	proxyConn = nil
-
-	hostname := req.URL.Hostname()
-	uConn := utls.UClient(peekConn, &utls.Config{ServerName: hostname}, utls.HelloChrome_131)
-	if d, ok := ctx.Deadline(); ok {
-		peekConn.SetDeadline(d)
-	}
-	if err := uConn.Handshake(); err != nil {
-		peekConn.Close()
-		return nil, err
-	}
+	// Туннель живёт в пуле дольше одного запроса — снимаем дедлайн.
+	proxyConn.SetDeadline(time.Time{})
 
-	tr := &http2.Transport{}
-	cc, err := tr.NewClientConn(uConn)
-	if err != nil {
-		uConn.Close()
-		return nil, err
-	}
+	conn := &peekConn{Conn: proxyConn, peek: br}
+	proxyConn = nil
+	return conn, nil
+}
 
-	reqToSend := req.Clone(ctx)
+// rotatingUTLSTransport берёт прокси из ProxyPool на каждый запрос
+// (или закреплённый за сессией) и сообщает пулу об ошибках CONNECT, TLS и 403.
 
-	httpResp, err := cc.RoundTrip(reqToSend)
-	if err != nil {
-		cc.Close()
-		return nil, err
-	}
-	httpResp.Body = &closeWrapper{ReadCloser: httpResp.Body, onClose: func() { _ = cc.Close() }}
-	return httpResp, nil
+type rotatingUTLSTransport struct {
+	client *Client
+	pool   *ProxyPool
 }
 
-type closeWrapper struct {
-	io.ReadCloser
-	onClose func()
-}
+func (t *rotatingUTLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Обычно прокси уже выбран в Client.send и лежит в контексте запроса.
+	proxyURL := ProxyFromContext(req.Context())
+	if proxyURL == nil {
+		proxyURL = t.client.proxyForRequest()
+	}
+	dial := func(ctx context.Context, addr string) (net.Conn, error) {
+		return dialViaProxy(ctx, proxyURL, addr)
+	}
 
-func (c *closeWrapper) Close() error {
-	err := c.ReadCloser.Close()
-	if c.onClose != nil {
-		c.onClose()
-		c.onClose = nil
+	resp, err := t.client.pool.roundTrip(req, proxyURL.String(), dial)
+	var de *dialError
+	switch {
+	case err != nil:
+		if errors.As(err, &de) && !de.shared && req.Context().Err() == nil {
+			t.pool.MarkFailure(proxyURL, de.stage)
+		}
+	case resp.StatusCode == http.StatusForbidden:
+		t.pool.MarkFailure(proxyURL, "403")
+	default:
+		t.pool.MarkSuccess(proxyURL)
 	}
-	return err
+	return resp, err
 }
 
 // peekConn
@@ -245,19 +295,24 @@ func (c *Client) ExtractSessionToken() (string, error) {
 // - sessiontoken
 // - x-device-id
 // - x-user-session-id
-// - sec-ch-*
+// - sec-ch-* (только у профилей Chromium)
 // Cookie НЕ устанавливается вручную — используется cookiejar.
 
 func (c *Client) setHeaders(req *http.Request) {
-	req.Header.Set("User-Agent", userAgent)
+	p := c.profile
+	req.Header.Set("User-Agent", p.UserAgent)
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
-	req.Header.Set("Accept-Language", "ru,en-US;q=0.9")
+	req.Header.Set("Accept-Encoding", p.AcceptEncoding)
+	req.Header.Set("Accept-Language", p.AcceptLanguage)
 	req.Header.Set("client", clientVer) // ← может потребоваться обновить на реальное значение из браузера
-	req.Header.Set("x-delivery-mode", "pickup")
-	req.Header.Set("x-domain", "moscow")
+	region, mode, storeID := c.storeHeaders(req.Context())
+	req.Header.Set("x-delivery-mode", string(mode))
+	req.Header.Set("x-domain", region)
 	req.Header.Set("x-platform", "omniweb")
-	req.Header.Set("x-retail-brand", "lo")
+	req.Header.Set("x-retail-brand", c.retailBrand())
+	if storeID != 0 {
+		req.Header.Set("x-store-id", strconv.Itoa(storeID))
+	}
 	req.Header.Set("x-device-id", c.cfg.DeviceID)
 	req.Header.Set("x-user-session-id", c.cfg.UserSessionID)
 
@@ -269,32 +324,155 @@ func (c *Client) setHeaders(req *http.Request) {
 
 	req.Header.Set("Referer", "https://lenta.com/catalog/moloko-128/")
 	req.Header.Set("Origin", "https://lenta.com")
-	req.Header.Set("sec-ch-ua", `"Google Chrome";v="143", "Chromium";v="143", "Not.A/Brand";v="24"`) // обнови под 2026
-	req.Header.Set("sec-ch-ua-mobile", "?0")
-	req.Header.Set("sec-ch-ua-platform", `"Windows"`)
+	for k, v := range p.clientHints() {
+		req.Header.Set(k, v)
+	}
 	req.Header.Set("Sec-Fetch-Dest", "empty")
 	req.Header.Set("Sec-Fetch-Mode", "cors")
 	req.Header.Set("Sec-Fetch-Site", "same-origin")
 }
 
+// ErrCanceled — запрос прерван отменой или дедлайном контекста.
+// Ошибка дополнительно оборачивает context.Canceled / context.DeadlineExceeded.
+var ErrCanceled = errors.New("запрос отменён")
+
+// canceled оборачивает err в ErrCanceled, если контекст уже завершён.
+func canceled(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ErrCanceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+}
+
+// DoContext выполняет запрос с контекстом ctx (см. Do).
+
+func (c *Client) DoContext(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return c.Do(req.WithContext(ctx))
+}
+
 // Do выполняет HTTP-запрос.
+// Тело ответа распаковывается по Content-Encoding (gzip, deflate, br, zstd).
+// Сетевые ошибки, ошибки прокси/TLS, 5xx и 429 повторяются по RetryPolicy
+// клиента (Config.Retry) с экспоненциальной паузой и учётом Retry-After.
+// Если задан SessionWarmer и API отклонил сессию (401/403), сессия
+// перепрогревается и запрос прозрачно повторяется.
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doSession(req)
+
+		kind, retryAfter := retryKind(resp, err)
+		delay, again := c.retry.next(attempt, kind, retryAfter)
+		if !again {
+			return resp, err
+		}
+		retry, ok := rewindRequest(req)
+		if !ok {
+			return resp, err
+		}
+
+		status := "нет ответа"
+		if resp != nil {
+			status = resp.Status
+			resp.Body.Close()
+		}
+		log.Printf("[RETRY] %s %s: %s (%s), попытка %d через %s",
+			req.Method, req.URL.Path, kind, status, attempt+1, delay.Round(time.Millisecond))
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, canceled(ctx, err)
+		}
+		req = retry
+	}
+}
+
+// doSession выполняет запрос, перепрогревая сессию при отказе 401/403.
+
+func (c *Client) doSession(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for {
+		gen, canRefresh := c.prepareRequest(req)
+		resp, err := c.send(req)
+		if err != nil {
+			return nil, canceled(ctx, err)
+		}
+		if !canRefresh || !isSessionRejected(resp) {
+			return resp, nil
+		}
+
+		retry, ok := rewindRequest(req)
+		if !ok {
+			return resp, nil
+		}
+		if rerr := c.refreshSession(ctx, gen); rerr != nil {
+			if ctx.Err() != nil {
+				resp.Body.Close()
+				return nil, canceled(ctx, rerr)
+			}
+			log.Printf("[WARN] Сессия не обновлена: %v", rerr)
+			return resp, nil
+		}
+		resp.Body.Close()
+		req = retry
+	}
+}
+
+// send выполняет один HTTP-запрос и распаковывает ответ.
+// Ошибки транспорта и распаковки возвращаются как *APIError.
 // При ошибке или статусе >=400 выполняется dump запроса и ответа
 // для отладки anti-bot блокировок.
 
-func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	c.setHeaders(req)
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	// Прокси выбирается до ожидания лимитера: у каждого прокси свой бакет.
+	// Ожидание идёт вне http.Client, чтобы не съедать его Timeout.
+	proxy := c.requestProxy()
+	if c.cfg.ProxyPool != nil {
+		req = req.WithContext(WithProxy(ctx, proxy))
+	}
+	endpoint, proxyKey := req.URL.Path, ""
+	if proxy != nil {
+		proxyKey = proxy.Host
+	}
+	if err := c.limiter.wait(ctx, endpoint, proxyKey); err != nil {
+		return nil, canceled(ctx, err)
+	}
+
 	resp, err := c.inner.Do(req)
+	if err != nil {
+		err = newTransportError(req, err)
+	} else if decErr := decodeResponseBody(resp); decErr != nil {
+		resp.Body.Close()
+		err = &APIError{Kind: KindDecode, StatusCode: resp.StatusCode, Endpoint: endpointOf(req), Err: decErr}
+		resp = nil
+	}
+
+	kind := KindOf(err)
 	if err != nil || resp.StatusCode >= 400 {
 		dump, _ := httputil.DumpRequestOut(req, true)
 		log.Printf("[DEBUG] Запрос:\n%s", string(dump))
 		if resp != nil {
 			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			resp.Body = io.NopCloser(bytes.NewReader(body))
 			log.Printf("[DEBUG] Ответ: %s", string(body))
+			kind = classifyStatus(resp, body)
 		}
 	}
+	c.limiter.observe(endpoint, proxyKey, kind)
 	return resp, err
 }
 
+// requestProxy — прокси для очередного запроса (nil — прямое подключение).
+func (c *Client) requestProxy() *url.URL {
+	if c.cfg.ProxyPool != nil {
+		return c.proxyForRequest()
+	}
+	return c.proxyURL
+}
+
 func (c *Client) BaseURL() string {
 	return baseURL
 }
@@ -308,3 +486,93 @@ func (c *Client) SetCookies(cookies []*http.Cookie) {
 	}
 	c.inner.Jar.SetCookies(u, cookies)
 }
+
+// PoolStats возвращает статистику пула HTTP/2 соединений.
+func (c *Client) PoolStats() PoolStats {
+	return c.pool.Stats()
+}
+
+// Close закрывает все соединения пула.
+func (c *Client) Close() {
+	c.pool.Close()
+}
+
+// proxyForRequest выбирает прокси из пула для очередного запроса.
+// При PinProxy используется закреплённый прокси, пока он здоров.
+
+func (c *Client) proxyForRequest() *url.URL {
+	pool := c.cfg.ProxyPool
+	if !c.cfg.PinProxy {
+		return pool.Pick()
+	}
+
+	c.proxyMu.Lock()
+	defer c.proxyMu.Unlock()
+	c.repinLocked()
+	return c.pinned
+}
+
+func (c *Client) repinLocked() {
+	pool := c.cfg.ProxyPool
+	if c.pinned != nil && pool.Healthy(c.pinned) {
+		return
+	}
+	prev := c.pinned
+	c.pinned = pool.Pick()
+	if prev != nil {
+		log.Printf("[WARN] Закреплённый прокси %s нездоров — переключение на %s, сессию может потребоваться перепрогреть",
+			prev.Redacted(), c.pinned.Redacted())
+	}
+}
+
+// SessionProxy возвращает прокси, через который должен идти прогрев сессии:
+// закреплённый из пула, единственный из Config.ProxyURL или nil (прямое подключение).
+// Без PinProxy браузер идёт через любой прокси пула.
+
+func (c *Client) SessionProxy() *url.URL {
+	if c.cfg.ProxyPool == nil {
+		return c.proxyURL
+	}
+	if !c.cfg.PinProxy {
+		return c.cfg.ProxyPool.Pick()
+	}
+
+	c.proxyMu.Lock()
+	defer c.proxyMu.Unlock()
+	c.repinLocked()
+	return c.pinned
+}
+
+// pinProxy закрепляет прокси сохранённой сессии, если он есть в пуле.
+func (c *Client) pinProxy(raw string) {
+	if raw == "" || c.cfg.ProxyPool == nil || !c.cfg.PinProxy {
+		return
+	}
+	u := c.cfg.ProxyPool.Lookup(raw)
+	if u == nil {
+		return
+	}
+
+	c.proxyMu.Lock()
+	defer c.proxyMu.Unlock()
+	c.pinned = u
+}
+
+// currentSessionProxy — прокси текущей сессии без выбора нового (для сохранения на диск).
+func (c *Client) currentSessionProxy() string {
+	c.proxyMu.Lock()
+	defer c.proxyMu.Unlock()
+
+	switch {
+	case c.pinned != nil:
+		return c.pinned.String()
+	case c.proxyURL != nil:
+		return c.proxyURL.String()
+	}
+	return ""
+}
+
+// Profile возвращает профиль браузера клиента.
+func (c *Client) Profile() *BrowserProfile {
+	return c.profile
+}
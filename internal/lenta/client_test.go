@@ -0,0 +1,131 @@
+package lenta
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Клиент логирует каждый запрос — в тестах это шум.
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// newTestClient возвращает клиент, запросы которого обслуживает handler
+// без сети и TLS. Повторы выключены.
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	c, err := NewClient(&Config{
+		Domain:       "lenta.com",
+		SessionToken: "test-token",
+		Retry:        &RetryPolicy{MaxAttempts: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.inner.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		resp := rec.Result()
+		resp.Request = req
+		return resp, nil
+	})
+	t.Cleanup(c.Close)
+	return c
+}
+
+// catalogHandler отдаёт категорию из total товаров с ID categoryId*1000+i.
+
+func catalogHandler(total int) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var body struct {
+			CategoryID int `json:"categoryId"`
+			Offset     int `json:"offset"`
+			Limit      int `json:"limit"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := CatalogItemsResponse{Items: []Product{}, Total: total}
+		for i := body.Offset; i < min(body.Offset+body.Limit, total); i++ {
+			id := body.CategoryID*1000 + i
+			resp.Items = append(resp.Items, Product{ID: id, Name: fmt.Sprintf("товар %d", id), Slug: fmt.Sprint(id)})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// connectProxy — HTTP-прокси с CONNECT: проверяет логин и эхом
+// возвращает всё, что пришло в туннель.
+func connectProxy(t *testing.T, target, auth string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Host != target {
+			http.Error(w, "ожидался CONNECT "+target, http.StatusBadRequest)
+			return
+		}
+		if got := r.Header.Get("Proxy-Authorization"); got != auth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		brw.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+		brw.Flush()
+		io.Copy(conn, brw)
+	}
+}
+
+func TestDialHTTPConnect(t *testing.T) {
+	const target = "lenta.com:443"
+	auth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:secret"))
+
+	plain := httptest.NewServer(connectProxy(t, target, auth))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(connectProxy(t, target, auth))
+	defer secure.Close()
+	proxyRootCAs = x509.NewCertPool()
+	proxyRootCAs.AddCert(secure.Certificate())
+	defer func() { proxyRootCAs = nil }()
+
+	for _, srv := range []*httptest.Server{plain, secure} {
+		u, _ := url.Parse(srv.URL)
+		u.User = url.UserPassword("user", "secret")
+		conn, err := dialViaProxy(context.Background(), u, target)
+		if err != nil {
+			t.Fatalf("%s: %v", u.Scheme, err)
+		}
+		checkTunnel(t, conn)
+	}
+
+	// Сертификат прокси проверяется: недоверенный — ошибка до отправки логина.
+	proxyRootCAs = x509.NewCertPool()
+	u, _ := url.Parse(secure.URL)
+	if conn, err := dialViaProxy(context.Background(), u, target); err == nil {
+		conn.Close()
+		t.Error("https-прокси с недоверенным сертификатом принят")
+	}
+}
@@ -0,0 +1,143 @@
+package lenta
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// PriceRow — цены одного товара во всех магазинах сравнения.
+// Цены в копейках, как в Prices; 0 — товара нет в магазине.
+
+type PriceRow struct {
+	ProductID int
+	Name      string
+	Slug      string
+	Prices    []int // по индексу цели в PriceComparison.Targets
+	Min       int
+	Max       int
+	Spread    int  // Max - Min
+	Missing   bool // товара нет хотя бы в одном магазине
+}
+
+// PriceComparison — широкая таблица цен: строка на товар, колонка на магазин.
+
+type PriceComparison struct {
+	Targets []StoreTarget
+	Rows    []PriceRow
+}
+
+// ComparePrices обходит категории в каждой цели и объединяет цены по ID товара.
+//
+// Все цели обходятся одной сессией клиента: регион и магазин подменяются
+// в заголовках через WithStoreTarget. Ошибка категории логируется и обход
+// продолжается; при отмене контекста или дрейфе схемы в строгом режиме
+// возвращается то, что успели собрать.
+
+func ComparePrices(ctx context.Context, client *Client, targets []StoreTarget, categoryIDs []int, opts IterateOptions) (*PriceComparison, error) {
+	pc := &PriceComparison{Targets: targets}
+	rows := make(map[int]*PriceRow)
+
+	for ti, t := range targets {
+		tctx := WithStoreTarget(ctx, t)
+		for _, catID := range categoryIDs {
+			for item, err := range IterateCategory(tctx, client, catID, opts) {
+				if err != nil {
+					if errors.Is(err, ErrCanceled) || errors.Is(err, ErrSchemaDrift) {
+						pc.build(rows)
+						return pc, err
+					}
+					log.Printf("Ошибка категории %d в %s: %v", catID, t.Name(), err)
+					break
+				}
+
+				row, ok := rows[item.ID]
+				if !ok {
+					row = &PriceRow{ProductID: item.ID, Name: item.Name, Slug: item.Slug, Prices: make([]int, len(targets))}
+					rows[item.ID] = row
+				}
+				row.Prices[ti] = item.Prices.Price
+			}
+		}
+		log.Printf("Сравнение: %s обойден (%d/%d)", t.Name(), ti+1, len(targets))
+	}
+
+	pc.build(rows)
+	return pc, nil
+}
+
+// build считает min/max/spread и сортирует строки по ID товара.
+func (pc *PriceComparison) build(rows map[int]*PriceRow) {
+	pc.Rows = pc.Rows[:0]
+	for _, row := range rows {
+		row.Min, row.Max, row.Missing = 0, 0, false
+		for _, p := range row.Prices {
+			if p == 0 {
+				row.Missing = true
+				continue
+			}
+			if row.Min == 0 || p < row.Min {
+				row.Min = p
+			}
+			row.Max = max(row.Max, p)
+		}
+		row.Spread = row.Max - row.Min
+		pc.Rows = append(pc.Rows, *row)
+	}
+	sort.Slice(pc.Rows, func(i, j int) bool { return pc.Rows[i].ProductID < pc.Rows[j].ProductID })
+}
+
+// ExportComparisonCSV сохраняет сравнение в CSV (разделитель ';', цены в рублях).
+// Колонки: id, name, url, цена в каждой цели, min, max, spread, missing.
+// Пустая ячейка цены — товара нет в магазине.
+
+func ExportComparisonCSV(pc *PriceComparison, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil && path != "" {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Comma = ';'
+
+	header := []string{"id", "name", "url"}
+	for _, t := range pc.Targets {
+		header = append(header, t.Name())
+	}
+	header = append(header, "min", "max", "spread", "missing")
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range pc.Rows {
+		rec := []string{strconv.Itoa(row.ProductID), row.Name, baseURL + "/p/" + row.Slug}
+		for _, p := range row.Prices {
+			rec = append(rec, fmtKopecks(p))
+		}
+		rec = append(rec, fmtKopecks(row.Min), fmtKopecks(row.Max), fmtPrice(float64(row.Spread)/100), strconv.FormatBool(row.Missing))
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// fmtKopecks форматирует цену в копейках; 0 — пустая ячейка.
+func fmtKopecks(p int) string {
+	if p == 0 {
+		return ""
+	}
+	return fmtPrice(float64(p) / 100)
+}
@@ -1,9 +1,40 @@
 package lenta
 
+import "time"
+
 type Config struct {
 	ProxyURL      string
 	SessionToken  string
 	Domain        string
 	DeviceID      string
 	UserSessionID string
+
+	// Регион и магазин определяют цены и ассортимент.
+	Region       string       // x-domain: moscow, spb, kazan…; "" — DefaultRegion
+	DeliveryMode DeliveryMode // x-delivery-mode; "" — DeliveryPickup
+	RetailBrand  string       // x-retail-brand; "" — DefaultRetailBrand
+	StoreID      int          // x-store-id — магазин самовывоза; 0 — магазин региона по умолчанию
+
+	// ProxyPool — ротация нескольких прокси. Если задан, ProxyURL игнорируется.
+	ProxyPool *ProxyPool
+	// PinProxy закрепляет один прокси из пула за прогретой сессией,
+	// чтобы cookies и exit IP совпадали. Прокси меняется, только когда уходит в cooldown.
+	PinProxy bool
+
+	// Profile — профиль браузера (TLS fingerprint, UA, client hints).
+	// nil — DefaultProfileName.
+	Profile *BrowserProfile
+
+	// Retry — политика повторов. nil — DefaultRetryPolicy().
+	Retry *RetryPolicy
+
+	// RateLimit — лимиты частоты запросов клиента. nil — без ограничений.
+	RateLimit *RateLimitConfig
+
+	// Schema — проверка схемы ответов (дрейф API). nil — выключена.
+	Schema *SchemaTracker
+
+	// IdleConnTimeout — сколько простаивающее HTTP/2 соединение живёт в пуле.
+	// 0 — значение по умолчанию (90s).
+	IdleConnTimeout time.Duration
 }
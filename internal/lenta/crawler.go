@@ -0,0 +1,177 @@
+package lenta
+
+import (
+	"context"
+	"sync"
+)
+
+// CrawlOptions — параметры параллельного обхода категорий.
+
+type CrawlOptions struct {
+	Workers int  // число одновременно обходимых категорий, 0 — 1
+	Ordered bool // выдавать результаты в порядке списка категорий
+
+	// Clients — отдельный клиент на воркер (воркер i берёт Clients[i%len]).
+	// Пусто — все воркеры делят один клиент: пул соединений, сессия
+	// и ограничитель скорости у него общие, так что RPS остаётся глобальным.
+	Clients []*Client
+
+	// Options возвращает параметры обхода категории (StartOffset, Seen и т.п.).
+	// nil — IterateOptions{} для всех. OnPage из результата вызывается
+	// в горутине воркера.
+	Options func(categoryID int) IterateOptions
+}
+
+// CrawlResult — очередная порция результатов обхода.
+//
+// Для каждой категории приходят результаты страниц (Products — новые товары
+// страницы Page), затем ровно один результат с Done. Err заполняется только
+// в нём: nil — категория обойдена полностью.
+
+type CrawlResult struct {
+	Index      int // позиция категории в списке
+	CategoryID int
+	Page       PageInfo
+	Products   []Product
+	Done       bool
+	Err        error
+}
+
+// Crawl обходит категории пулом из opts.Workers воркеров.
+//
+// Без Ordered результаты разных категорий перемешаны, но внутри категории
+// страницы идут по порядку. С Ordered результаты категории выдаются только
+// после того, как выданы все предыдущие: пока первая категория не обойдена,
+// страницы остальных копятся в памяти.
+//
+// При отмене ctx воркеры не берут новые категории, а текущие завершаются
+// результатом с ErrCanceled. Канал закрывается, когда все воркеры остановились;
+// читать его нужно до закрытия, иначе воркеры заблокируются.
+
+func Crawl(ctx context.Context, client *Client, categoryIDs []int, opts CrawlOptions) <-chan CrawlResult {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	workers = min(workers, max(len(categoryIDs), 1))
+
+	jobs := make(chan int)
+	results := make(chan CrawlResult, workers)
+
+	go func() {
+		defer close(jobs)
+		for i := range categoryIDs {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		c := client
+		if len(opts.Clients) > 0 {
+			c = opts.Clients[w%len(opts.Clients)]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				crawlCategory(ctx, c, i, categoryIDs[i], opts.Options, results)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	if !opts.Ordered {
+		return results
+	}
+	return reorder(results)
+}
+
+// crawlCategory обходит одну категорию и отправляет результаты постранично.
+// Товары страницы копятся до следующего OnPage: IterateCategory сообщает
+// о странице до выдачи её товаров.
+
+func crawlCategory(ctx context.Context, client *Client, index, categoryID int, options func(int) IterateOptions, out chan<- CrawlResult) {
+	var opts IterateOptions
+	if options != nil {
+		opts = options(categoryID)
+	}
+
+	var page *PageInfo
+	var products []Product
+	flush := func() {
+		if page != nil {
+			out <- CrawlResult{Index: index, CategoryID: categoryID, Page: *page, Products: products}
+		}
+		page, products = nil, nil
+	}
+
+	onPage := opts.OnPage
+	opts.OnPage = func(p PageInfo) {
+		flush()
+		page = &p
+		if onPage != nil {
+			onPage(p)
+		}
+	}
+
+	var crawlErr error
+	for item, err := range IterateCategory(ctx, client, categoryID, opts) {
+		if err != nil {
+			crawlErr = err
+			break
+		}
+		products = append(products, item)
+	}
+	flush()
+	out <- CrawlResult{Index: index, CategoryID: categoryID, Done: true, Err: crawlErr}
+}
+
+// reorder выдаёт результаты категорий в порядке Index.
+func reorder(in <-chan CrawlResult) <-chan CrawlResult {
+	out := make(chan CrawlResult)
+	go func() {
+		defer close(out)
+		pending := make(map[int][]CrawlResult)
+		done := make(map[int]bool)
+		next := 0
+		for r := range in {
+			if r.Index != next {
+				pending[r.Index] = append(pending[r.Index], r)
+				done[r.Index] = done[r.Index] || r.Done
+				continue
+			}
+			out <- r
+			if !r.Done {
+				continue
+			}
+			// Категория завершена — выдаём накопленное по следующим.
+			for next++; ; next++ {
+				for _, p := range pending[next] {
+					out <- p
+				}
+				delete(pending, next)
+				if !done[next] {
+					break
+				}
+				delete(done, next)
+			}
+		}
+		// Канал закрылся, а категории между ними не дошли (отмена до старта).
+		for len(pending) > 0 {
+			next++
+			for _, p := range pending[next] {
+				out <- p
+			}
+			delete(pending, next)
+		}
+	}()
+	return out
+}
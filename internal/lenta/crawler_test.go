@@ -0,0 +1,103 @@
+package lenta
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestCrawlCheckpoint повторяет схему lenta-parser: воркеры читают позицию
+// из checkpoint-а, основной цикл записывает товары и страницы. Запускать с -race.
+func TestCrawlCheckpoint(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		name := "unordered"
+		if ordered {
+			name = "ordered"
+		}
+		t.Run(name, func(t *testing.T) {
+			const total = 95 // три страницы по 40
+			client := newTestClient(t, catalogHandler(total))
+			cp, err := NewCheckpoint(filepath.Join(t.TempDir(), "crawl.json"), "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cp.Close()
+
+			ids := []int{1, 2, 3, 4, 5, 6, 7, 8}
+			results := Crawl(context.Background(), client, ids, CrawlOptions{
+				Workers: 4,
+				Ordered: ordered,
+				Options: func(categoryID int) IterateOptions {
+					return IterateOptions{PageSize: 40, StartOffset: cp.ResumeOffset(categoryID)}
+				},
+			})
+
+			counts := make(map[int]int)
+			lastIndex := 0
+			for res := range results {
+				if ordered && res.Index < lastIndex {
+					t.Fatalf("категория #%d после #%d в режиме Ordered", res.Index, lastIndex)
+				}
+				lastIndex = res.Index
+
+				if res.Done {
+					if res.Err != nil {
+						t.Fatalf("категория %d: %v", res.CategoryID, res.Err)
+					}
+					if err := cp.Complete(res.CategoryID); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				for i := range res.Products {
+					if err := cp.Add(res.CategoryID, &res.Products[i]); err != nil {
+						t.Fatal(err)
+					}
+				}
+				counts[res.CategoryID] += len(res.Products)
+				if err := cp.Page(res.Page); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, id := range ids {
+				if counts[id] != total {
+					t.Errorf("категория %d: %d товаров, ожидалось %d", id, counts[id], total)
+				}
+				if !cp.IsCompleted(id) {
+					t.Errorf("категория %d не отмечена завершённой", id)
+				}
+			}
+			if cp.Items != total*len(ids) {
+				t.Errorf("Items = %d, ожидалось %d", cp.Items, total*len(ids))
+			}
+		})
+	}
+}
+
+// TestCrawlCancel проверяет, что после отмены канал закрывается,
+// а незавершённые категории заканчиваются ErrCanceled.
+func TestCrawlCancel(t *testing.T) {
+	client := newTestClient(t, catalogHandler(1000))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ids := []int{1, 2, 3, 4, 5, 6}
+	done := make(map[int]error)
+	for res := range Crawl(ctx, client, ids, CrawlOptions{Workers: 2}) {
+		cancel()
+		if res.Done {
+			done[res.CategoryID] = res.Err
+		}
+	}
+
+	if len(done) == len(ids) {
+		t.Fatal("после отмены обойдены все категории")
+	}
+	for id, err := range done {
+		if !errors.Is(err, ErrCanceled) {
+			t.Errorf("категория %d: err = %v, ожидалась ErrCanceled", id, err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package lenta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// decodeResponseBody распаковывает тело ответа по Content-Encoding.
+//
+// Accept-Encoding выставляется вручную в setHeaders, поэтому транспорт Go
+// сам ничего не распаковывает. После декодирования Content-Encoding и
+// Content-Length удаляются, ContentLength = -1, Uncompressed = true —
+// вызывающий код видит обычный JSON.
+//
+// Ответы без тела (HEAD, 204, 304, пустые 403-заглушки) не распаковываются:
+// декодер упал бы на EOF, и вместо статуса вызывающий код увидел бы KindDecode.
+
+func decodeResponseBody(resp *http.Response) error {
+	encodings := parseContentEncoding(resp.Header.Get("Content-Encoding"))
+	if len(encodings) == 0 || !hasBody(resp) {
+		return nil
+	}
+
+	body := resp.Body
+	peek := bufio.NewReader(body)
+	if _, err := peek.Peek(1); err == io.EOF {
+		return nil // тело пустое — читать нечего
+	}
+	var r io.Reader = peek
+	var closers []func() error
+	// При ошибке на очередном слое закрываем уже открытые декодеры —
+	// исходное тело закрывает вызывающий код.
+	fail := func(err error) error {
+		closeDecoders(closers)
+		return err
+	}
+
+	// Кодировки применяются в порядке перечисления — снимаем их с конца.
+	for i := len(encodings) - 1; i >= 0; i-- {
+		switch encodings[i] {
+		case "gzip", "x-gzip":
+			zr, err := gzip.NewReader(r)
+			if err != nil {
+				return fail(fmt.Errorf("gzip: %w", err))
+			}
+			r = zr
+			closers = append(closers, zr.Close)
+		case "deflate":
+			// По RFC deflate — это zlib-поток, но часть серверов шлёт «сырой» deflate.
+			br := bufio.NewReader(r)
+			if isZlibHeader(br) {
+				zr, err := zlib.NewReader(br)
+				if err != nil {
+					return fail(fmt.Errorf("deflate: %w", err))
+				}
+				r = zr
+				closers = append(closers, zr.Close)
+			} else {
+				fr := flate.NewReader(br)
+				r = fr
+				closers = append(closers, fr.Close)
+			}
+		case "br":
+			r = brotli.NewReader(r)
+		case "zstd":
+			zr, err := zstd.NewReader(r)
+			if err != nil {
+				return fail(fmt.Errorf("zstd: %w", err))
+			}
+			r = zr
+			closers = append(closers, func() error { zr.Close(); return nil })
+		case "identity":
+		default:
+			return fail(fmt.Errorf("неподдерживаемый Content-Encoding: %s", encodings[i]))
+		}
+	}
+
+	resp.Body = &decodedBody{Reader: r, closers: closers, raw: body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// hasBody — может ли у ответа быть тело (RFC 9110, раздел 6.4.1).
+func hasBody(resp *http.Response) bool {
+	switch {
+	case resp.StatusCode == http.StatusNoContent, resp.StatusCode == http.StatusNotModified:
+		return false
+	case resp.Request != nil && resp.Request.Method == http.MethodHead:
+		return false
+	}
+	return resp.ContentLength != 0
+}
+
+func parseContentEncoding(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if enc := strings.ToLower(strings.TrimSpace(part)); enc != "" {
+			out = append(out, enc)
+		}
+	}
+	return out
+}
+
+// isZlibHeader проверяет заголовок zlib (RFC 1950): CM=8 и FCHECK.
+func isZlibHeader(br *bufio.Reader) bool {
+	h, err := br.Peek(2)
+	if err != nil {
+		return false
+	}
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
+
+// decodedBody закрывает цепочку декодеров и исходное тело ответа.
+type decodedBody struct {
+	io.Reader
+	closers []func() error
+	raw     io.ReadCloser
+}
+
+func (d *decodedBody) Close() error {
+	closeDecoders(d.closers)
+	d.closers = nil
+	return d.raw.Close()
+}
+
+// closeDecoders закрывает декодеры от внешнего к внутреннему.
+func closeDecoders(closers []func() error) {
+	for i := len(closers) - 1; i >= 0; i-- {
+		closers[i]()
+	}
+}
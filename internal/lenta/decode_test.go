@@ -0,0 +1,135 @@
+package lenta
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/flate"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+func compress(t *testing.T, enc string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch enc {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "deflate-raw":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	default:
+		t.Fatalf("неизвестная кодировка %s", enc)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestDecodeResponseBody(t *testing.T) {
+	payload := []byte(`{"items":[{"id":1,"name":"Молоко"}]}`)
+	tests := []struct {
+		header string
+		body   []byte
+	}{
+		{"gzip", compress(t, "gzip", payload)},
+		{"deflate", compress(t, "deflate", payload)},
+		{"deflate", compress(t, "deflate-raw", payload)},
+		{"br", compress(t, "br", payload)},
+		{"zstd", compress(t, "zstd", payload)},
+		{"gzip, br", compress(t, "br", compress(t, "gzip", payload))},
+		{"identity", payload},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{
+			StatusCode:    http.StatusOK,
+			Header:        http.Header{"Content-Encoding": {tt.header}},
+			Body:          io.NopCloser(bytes.NewReader(tt.body)),
+			ContentLength: int64(len(tt.body)),
+		}
+		if err := decodeResponseBody(resp); err != nil {
+			t.Errorf("%s: %v", tt.header, err)
+			continue
+		}
+		got, err := io.ReadAll(resp.Body)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Errorf("%s: получено %q, %v", tt.header, got, err)
+		}
+		if resp.Header.Get("Content-Encoding") != "" || !resp.Uncompressed {
+			t.Errorf("%s: заголовки не сброшены после распаковки", tt.header)
+		}
+	}
+}
+
+func TestDecodeResponseBodyEmpty(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusNoContent, http.StatusNotModified} {
+		for _, length := range []int64{0, -1} {
+			resp := &http.Response{
+				StatusCode:    status,
+				Header:        http.Header{"Content-Encoding": {"gzip"}},
+				Body:          io.NopCloser(bytes.NewReader(nil)),
+				ContentLength: length,
+			}
+			if err := decodeResponseBody(resp); err != nil {
+				t.Errorf("%d (длина %d): %v", status, length, err)
+			}
+		}
+	}
+}
+
+// TestEmptyGzip403 — пустая 403 с Content-Encoding: gzip должна дойти до
+// вызывающего кода как отказ anti-bot, а не как ошибка распаковки.
+func TestEmptyGzip403(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(http.StatusForbidden)
+	})
+	_, err := FetchCategoryContext(context.Background(), client, 128, 0, 1)
+	if kind := KindOf(err); kind != KindAntiBot {
+		t.Fatalf("вид ошибки %s (%v), ожидался %s", kind, err, KindAntiBot)
+	}
+}
+
+// TestDecodeResponseBodyLayerError — ошибка внешнего слоя не маскируется
+// и не закрывает исходное тело: это делает вызывающий код.
+func TestDecodeResponseBodyLayerError(t *testing.T) {
+	body := compress(t, "zstd", []byte("это не gzip"))
+	raw := &closeCounter{Reader: bytes.NewReader(body)}
+	resp := &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Encoding": {"gzip, zstd"}},
+		Body:          raw,
+		ContentLength: int64(len(body)),
+	}
+	if err := decodeResponseBody(resp); err == nil {
+		t.Fatal("gzip поверх zstd без gzip-заголовка распакован без ошибки")
+	}
+	if resp.Body != raw || raw.closed != 0 {
+		t.Errorf("после ошибки тело подменено или закрыто (%d)", raw.closed)
+	}
+}
+
+type closeCounter struct {
+	io.Reader
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
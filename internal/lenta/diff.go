@@ -0,0 +1,385 @@
+package lenta
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PriceKey — товар в конкретном магазине.
+
+type PriceKey struct {
+	ProductID int
+	StoreID   int
+}
+
+// PriceRecord — цена товара в магазине на момент выгрузки или запуска.
+// Цены в копейках.
+
+type PriceRecord struct {
+	ProductID    int
+	StoreID      int
+	Name         string
+	URL          string
+	Price        int
+	PriceRegular int
+	Discount     string // бейдж скидки, например "-23%"
+}
+
+// Discounted — на товар действует скидка: есть бейдж или цена ниже обычной.
+func (r PriceRecord) Discounted() bool {
+	return r.Discount != "" || (r.PriceRegular > 0 && r.Price > 0 && r.Price < r.PriceRegular)
+}
+
+// Snapshot — цены одной выгрузки или одного запуска.
+
+type Snapshot map[PriceKey]PriceRecord
+
+// Add добавляет товар в снимок.
+func (s Snapshot) Add(p *Product) {
+	rec := PriceRecord{
+		ProductID:    p.ID,
+		StoreID:      p.StoreID,
+		Name:         p.Name,
+		URL:          p.URL(),
+		Price:        p.Prices.Price,
+		PriceRegular: p.Prices.PriceRegular,
+	}
+	if len(p.Badges.Discount) > 0 {
+		rec.Discount = p.Badges.Discount[0].Title
+	}
+	s[PriceKey{ProductID: p.ID, StoreID: p.StoreID}] = rec
+}
+
+// LoadSnapshot читает выгрузку Exporter-а: CSV, JSON или JSONL (формат — по расширению).
+// Краткий CSV (без ExportOptions.Full) не подходит: в нём нет ID товара.
+
+func LoadSnapshot(path string) (Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snap := make(Snapshot)
+	switch FormatFromPath(path) {
+	case FormatJSON:
+		var items []Product
+		if err := json.NewDecoder(f).Decode(&items); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for i := range items {
+			snap.Add(&items[i])
+		}
+	case FormatJSONL:
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for line := 1; sc.Scan(); line++ {
+			if len(strings.TrimSpace(sc.Text())) == 0 {
+				continue
+			}
+			var p Product
+			if err := json.Unmarshal(sc.Bytes(), &p); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+			snap.Add(&p)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		if err := readSnapshotCSV(f, snap); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return snap, nil
+}
+
+// readSnapshotCSV разбирает CSV с колонками productCSVHeader (цены в рублях).
+func readSnapshotCSV(r io.Reader, snap Snapshot) error {
+	cr := csv.NewReader(r)
+	cr.Comma = ';'
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	col := make(map[string]int, len(header))
+	for i, h := range header {
+		col[strings.TrimSpace(h)] = i
+	}
+	if _, ok := col["id"]; !ok {
+		return fmt.Errorf("нет колонки id — нужна полная выгрузка (lenta-parser -full) или json/jsonl")
+	}
+	get := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(get(rec, "id"))
+		if err != nil {
+			return fmt.Errorf("неверный id %q", get(rec, "id"))
+		}
+		storeID, _ := strconv.Atoi(get(rec, "store_id"))
+		r := PriceRecord{
+			ProductID:    id,
+			StoreID:      storeID,
+			Name:         get(rec, "name"),
+			URL:          get(rec, "url"),
+			Price:        parseKopecks(get(rec, "price")),
+			PriceRegular: parseKopecks(get(rec, "price_regular")),
+			Discount:     get(rec, "discount"),
+		}
+		snap[PriceKey{ProductID: id, StoreID: storeID}] = r
+	}
+}
+
+// parseKopecks — "123.45" → 12345; пустое или неверное значение — 0.
+func parseKopecks(s string) int {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Round(f * 100))
+}
+
+// ChangeKind — вид изменения между двумя снимками.
+
+type ChangeKind string
+
+const (
+	ChangeNew           ChangeKind = "new"              // товар появился
+	ChangeDelisted      ChangeKind = "delisted"         // товар пропал
+	ChangePriceUp       ChangeKind = "price_up"         // цена выросла
+	ChangePriceDown     ChangeKind = "price_down"       // цена снизилась
+	ChangeDiscountStart ChangeKind = "discount_started" // началась скидка
+	ChangeDiscountEnd   ChangeKind = "discount_ended"   // скидка закончилась
+)
+
+var changeOrder = map[ChangeKind]int{
+	ChangeNew: 0, ChangeDelisted: 1, ChangePriceUp: 2, ChangePriceDown: 3, ChangeDiscountStart: 4, ChangeDiscountEnd: 5,
+}
+
+// PriceChange — одно изменение товара в магазине.
+// Delta = NewPrice - OldPrice (копейки), DeltaPct — в процентах от OldPrice.
+
+type PriceChange struct {
+	Kind        ChangeKind `json:"kind"`
+	ProductID   int        `json:"productId"`
+	StoreID     int        `json:"storeId,omitempty"`
+	Name        string     `json:"name"`
+	URL         string     `json:"url,omitempty"`
+	OldPrice    int        `json:"oldPrice,omitempty"`
+	NewPrice    int        `json:"newPrice,omitempty"`
+	Delta       int        `json:"delta,omitempty"`
+	DeltaPct    float64    `json:"deltaPct,omitempty"`
+	OldDiscount string     `json:"oldDiscount,omitempty"`
+	NewDiscount string     `json:"newDiscount,omitempty"`
+}
+
+// DiffSnapshots сравнивает снимки по ID товара и магазину.
+// Товар с изменённой ценой и скидкой даёт два изменения.
+// Результат упорядочен по виду изменения и ID товара.
+
+func DiffSnapshots(old, cur Snapshot) []PriceChange {
+	var out []PriceChange
+	for key, n := range cur {
+		o, ok := old[key]
+		if !ok {
+			out = append(out, PriceChange{Kind: ChangeNew, ProductID: n.ProductID, StoreID: n.StoreID,
+				Name: n.Name, URL: n.URL, NewPrice: n.Price, NewDiscount: n.Discount})
+			continue
+		}
+
+		base := PriceChange{ProductID: n.ProductID, StoreID: n.StoreID, Name: n.Name, URL: n.URL,
+			OldPrice: o.Price, NewPrice: n.Price, OldDiscount: o.Discount, NewDiscount: n.Discount}
+		if o.Price != n.Price && o.Price > 0 && n.Price > 0 {
+			c := base
+			c.Kind = ChangePriceUp
+			if n.Price < o.Price {
+				c.Kind = ChangePriceDown
+			}
+			c.Delta = n.Price - o.Price
+			c.DeltaPct = math.Round(float64(c.Delta)/float64(o.Price)*10000) / 100
+			out = append(out, c)
+		}
+		switch {
+		case !o.Discounted() && n.Discounted():
+			c := base
+			c.Kind = ChangeDiscountStart
+			out = append(out, c)
+		case o.Discounted() && !n.Discounted():
+			c := base
+			c.Kind = ChangeDiscountEnd
+			out = append(out, c)
+		}
+	}
+	for key, o := range old {
+		if _, ok := cur[key]; !ok {
+			out = append(out, PriceChange{Kind: ChangeDelisted, ProductID: o.ProductID, StoreID: o.StoreID,
+				Name: o.Name, URL: o.URL, OldPrice: o.Price, OldDiscount: o.Discount})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Kind != b.Kind {
+			return changeOrder[a.Kind] < changeOrder[b.Kind]
+		}
+		if a.ProductID != b.ProductID {
+			return a.ProductID < b.ProductID
+		}
+		return a.StoreID < b.StoreID
+	})
+	return out
+}
+
+// DiffFormat — формат отчёта об изменениях.
+
+type DiffFormat string
+
+const (
+	DiffCSV      DiffFormat = "csv"
+	DiffJSON     DiffFormat = "json"
+	DiffMarkdown DiffFormat = "md"
+)
+
+// ParseDiffFormat разбирает формат отчёта из флага CLI.
+func ParseDiffFormat(s string) (DiffFormat, error) {
+	switch f := strings.ToLower(strings.TrimSpace(s)); f {
+	case "csv":
+		return DiffCSV, nil
+	case "json":
+		return DiffJSON, nil
+	case "md", "markdown":
+		return DiffMarkdown, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат отчёта: %q (csv, json, md)", s)
+	}
+}
+
+// DiffFormatFromPath определяет формат отчёта по расширению; неизвестное — Markdown.
+func DiffFormatFromPath(path string) DiffFormat {
+	if f, err := ParseDiffFormat(strings.TrimPrefix(filepath.Ext(path), ".")); err == nil {
+		return f
+	}
+	return DiffMarkdown
+}
+
+// WriteDiff пишет отчёт об изменениях в формате format. Цены — в рублях.
+func WriteDiff(w io.Writer, changes []PriceChange, format DiffFormat) error {
+	switch format {
+	case DiffCSV:
+		return writeDiffCSV(w, changes)
+	case DiffJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if changes == nil {
+			changes = []PriceChange{}
+		}
+		return enc.Encode(changes)
+	default:
+		return writeDiffMarkdown(w, changes)
+	}
+}
+
+func writeDiffCSV(w io.Writer, changes []PriceChange) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = ';'
+	if err := cw.Write([]string{"kind", "id", "store_id", "name", "old_price", "new_price", "delta", "delta_pct", "old_discount", "new_discount", "url"}); err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if err := cw.Write([]string{
+			string(c.Kind), strconv.Itoa(c.ProductID), strconv.Itoa(c.StoreID), c.Name,
+			fmtKopecks(c.OldPrice), fmtKopecks(c.NewPrice), fmtDelta(c), fmtDeltaPct(c),
+			c.OldDiscount, c.NewDiscount, c.URL,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var changeTitles = map[ChangeKind]string{
+	ChangeNew:           "Новые товары",
+	ChangeDelisted:      "Пропавшие товары",
+	ChangePriceUp:       "Подорожание",
+	ChangePriceDown:     "Подешевление",
+	ChangeDiscountStart: "Начались скидки",
+	ChangeDiscountEnd:   "Закончились скидки",
+}
+
+// writeDiffMarkdown — сводка по видам изменений и таблица на каждый вид.
+func writeDiffMarkdown(w io.Writer, changes []PriceChange) error {
+	byKind := make(map[ChangeKind][]PriceChange)
+	for _, c := range changes {
+		byKind[c.Kind] = append(byKind[c.Kind], c)
+	}
+	kinds := []ChangeKind{ChangeNew, ChangeDelisted, ChangePriceUp, ChangePriceDown, ChangeDiscountStart, ChangeDiscountEnd}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Изменения цен")
+	fmt.Fprintln(bw)
+	for _, k := range kinds {
+		fmt.Fprintf(bw, "- %s: %d\n", changeTitles[k], len(byKind[k]))
+	}
+
+	for _, k := range kinds {
+		list := byKind[k]
+		if len(list) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n## %s\n\n", changeTitles[k])
+		fmt.Fprintln(bw, "| ID | Магазин | Товар | Было | Стало | Δ ₽ | Δ % | Скидка |")
+		fmt.Fprintln(bw, "|---:|---:|---|---:|---:|---:|---:|---|")
+		for _, c := range list {
+			name := strings.ReplaceAll(c.Name, "|", "\\|")
+			if c.URL != "" {
+				name = "[" + name + "](" + c.URL + ")"
+			}
+			discount := c.NewDiscount
+			if k == ChangeDelisted || k == ChangeDiscountEnd {
+				discount = c.OldDiscount
+			}
+			fmt.Fprintf(bw, "| %d | %d | %s | %s | %s | %s | %s | %s |\n", c.ProductID, c.StoreID, name,
+				fmtKopecks(c.OldPrice), fmtKopecks(c.NewPrice), fmtDelta(c), fmtDeltaPct(c), discount)
+		}
+	}
+	return bw.Flush()
+}
+
+func fmtDelta(c PriceChange) string {
+	if c.Delta == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+.2f", float64(c.Delta)/100)
+}
+
+func fmtDeltaPct(c PriceChange) string {
+	if c.Delta == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+.2f", c.DeltaPct)
+}
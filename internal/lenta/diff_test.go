@@ -0,0 +1,93 @@
+package lenta
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	rec := func(id, price, regular int, discount string) PriceRecord {
+		return PriceRecord{ProductID: id, StoreID: 7, Price: price, PriceRegular: regular, Discount: discount}
+	}
+	snap := func(recs ...PriceRecord) Snapshot {
+		s := make(Snapshot)
+		for _, r := range recs {
+			s[PriceKey{ProductID: r.ProductID, StoreID: r.StoreID}] = r
+		}
+		return s
+	}
+	old := snap(
+		rec(1, 10000, 0, ""),       // подорожал
+		rec(2, 10000, 0, ""),       // подешевел со скидкой — два изменения
+		rec(3, 5000, 6000, "-17%"), // скидка закончилась
+		rec(4, 5000, 0, ""),        // пропал
+		rec(6, 0, 0, ""),           // цены не было — не сравниваем
+	)
+	cur := snap(
+		rec(1, 12500, 0, ""),
+		rec(2, 8000, 10000, ""),
+		rec(3, 6000, 6000, ""),
+		rec(5, 3000, 0, ""),
+		rec(6, 4000, 0, ""),
+	)
+
+	type change struct {
+		kind ChangeKind
+		id   int
+	}
+	want := []change{
+		{ChangeNew, 5},
+		{ChangeDelisted, 4},
+		{ChangePriceUp, 1},
+		{ChangePriceUp, 3},
+		{ChangePriceDown, 2},
+		{ChangeDiscountStart, 2},
+		{ChangeDiscountEnd, 3},
+	}
+	got := DiffSnapshots(old, cur)
+	if len(got) != len(want) {
+		t.Fatalf("изменений %d, ожидалось %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Kind != w.kind || got[i].ProductID != w.id {
+			t.Errorf("изменение %d: %s #%d, ожидалось %s #%d", i, got[i].Kind, got[i].ProductID, w.kind, w.id)
+		}
+	}
+	if up := got[2]; up.Delta != 2500 || up.DeltaPct != 25 {
+		t.Errorf("подорожание: delta %d, %v%%", up.Delta, up.DeltaPct)
+	}
+}
+
+func TestWriteDiff(t *testing.T) {
+	changes := []PriceChange{{Kind: ChangePriceDown, ProductID: 2, Name: "Хлеб; ржаной", OldPrice: 10000, NewPrice: 8000, Delta: -2000, DeltaPct: -20}}
+
+	var buf bytes.Buffer
+	if err := WriteDiff(&buf, changes, DiffCSV); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], `price_down;2;0;"Хлеб; ржаной";100.00;80.00;`) {
+		t.Errorf("CSV:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteDiff(&buf, nil, DiffJSON); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("пустой JSON = %q, ожидался []", buf.String())
+	}
+
+	for _, tt := range []struct {
+		in   string
+		want DiffFormat
+	}{{"CSV", DiffCSV}, {"markdown", DiffMarkdown}, {" json ", DiffJSON}} {
+		if f, err := ParseDiffFormat(tt.in); err != nil || f != tt.want {
+			t.Errorf("ParseDiffFormat(%q) = %q, %v", tt.in, f, err)
+		}
+	}
+	if _, err := ParseDiffFormat("xml"); err == nil {
+		t.Error("ParseDiffFormat(xml): ожидалась ошибка")
+	}
+}
@@ -0,0 +1,227 @@
+package lenta
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxErrorBody — сколько байт тела ответа сохраняется в APIError.
+const maxErrorBody = 512
+
+// ErrorKind — класс ошибки API, по которому вызывающий код решает,
+// что делать дальше: перепрогреть сессию, сменить прокси, подождать или сдаться.
+
+type ErrorKind int
+
+const (
+	KindUnknown      ErrorKind = iota
+	KindAntiBot                // 403 от Qrator / WAF (HTML-заглушка вместо JSON)
+	KindUnauthorized           // 401 или 403 с JSON — сессия/токен не приняты
+	KindRateLimited            // 429
+	KindServer                 // 5xx
+	KindBadRequest             // прочие 4xx — запрос неверен, повтор не поможет
+	KindDecode                 // ответ не распаковался или не соответствует схеме
+	KindProxy                  // не удалось подключиться к прокси / CONNECT отклонён
+	KindTLS                    // uTLS handshake не прошёл
+	KindNetwork                // сетевая ошибка при прямом подключении
+)
+
+// Sentinel-ошибки для errors.Is: errors.Is(err, ErrAntiBot) и т.д.
+var (
+	ErrAntiBot      = errors.New("anti-bot блокировка")
+	ErrUnauthorized = errors.New("сессия не авторизована")
+	ErrRateLimited  = errors.New("превышен лимит запросов")
+	ErrServer       = errors.New("ошибка сервера")
+	ErrBadRequest   = errors.New("некорректный запрос")
+	ErrDecode       = errors.New("ошибка разбора ответа")
+	ErrProxy        = errors.New("ошибка прокси")
+	ErrTLS          = errors.New("ошибка TLS")
+	ErrNetwork      = errors.New("сетевая ошибка")
+)
+
+var kindSentinels = map[ErrorKind]error{
+	KindAntiBot:      ErrAntiBot,
+	KindUnauthorized: ErrUnauthorized,
+	KindRateLimited:  ErrRateLimited,
+	KindServer:       ErrServer,
+	KindBadRequest:   ErrBadRequest,
+	KindDecode:       ErrDecode,
+	KindProxy:        ErrProxy,
+	KindTLS:          ErrTLS,
+	KindNetwork:      ErrNetwork,
+}
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindAntiBot:
+		return "AntiBot"
+	case KindUnauthorized:
+		return "Unauthorized"
+	case KindRateLimited:
+		return "RateLimited"
+	case KindServer:
+		return "Server"
+	case KindBadRequest:
+		return "BadRequest"
+	case KindDecode:
+		return "Decode"
+	case KindProxy:
+		return "Proxy"
+	case KindTLS:
+		return "TLS"
+	case KindNetwork:
+		return "Network"
+	default:
+		return "Unknown"
+	}
+}
+
+// APIError — ошибка обращения к API.
+// StatusCode == 0 — ответа не было (ошибка прокси, TLS или сети).
+
+type APIError struct {
+	Kind       ErrorKind
+	StatusCode int
+	Endpoint   string        // путь запроса, например /api-gateway/v1/catalog/items
+	RequestID  string        // идентификатор запроса из заголовков ответа, если есть
+	Body       string        // начало тела ответа (не больше maxErrorBody байт)
+	RetryAfter time.Duration // из заголовка Retry-After (429/503), 0 — не задан
+	Err        error         // исходная ошибка (транспорт, json), если есть
+}
+
+func (e *APIError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ошибка API %s [%s]", e.Endpoint, e.Kind)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, ": %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request-id %s)", e.RequestID)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	if e.Body != "" {
+		fmt.Fprintf(&b, ". Тело: %s", e.Body)
+	}
+	return b.String()
+}
+
+// Unwrap отдаёт sentinel вида ошибки и исходную ошибку —
+// работают и errors.Is(err, ErrRateLimited), и errors.Is(err, context.Canceled).
+func (e *APIError) Unwrap() []error {
+	var errs []error
+	if s, ok := kindSentinels[e.Kind]; ok {
+		errs = append(errs, s)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// KindOf возвращает вид ошибки API (KindUnknown, если err не APIError).
+func KindOf(err error) ErrorKind {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+	return KindUnknown
+}
+
+// newStatusError строит APIError по не-2xx ответу и уже прочитанному телу.
+
+func newStatusError(resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		Kind:       classifyStatus(resp, body),
+		StatusCode: resp.StatusCode,
+		Endpoint:   endpointOf(resp.Request),
+		RequestID:  requestID(resp.Header),
+		Body:       truncateBody(body),
+		RetryAfter: retryAfter(resp),
+	}
+}
+
+// newDecodeError — ответ 200, но тело не разобралось.
+func newDecodeError(resp *http.Response, body []byte, err error) *APIError {
+	e := newStatusError(resp, body)
+	e.Kind = KindDecode
+	e.Err = err
+	return e
+}
+
+// newTransportError классифицирует ошибку, после которой ответа нет.
+func newTransportError(req *http.Request, err error) *APIError {
+	kind := KindNetwork
+	var de *dialError
+	if errors.As(err, &de) {
+		switch {
+		case de.stage == "tls":
+			kind = KindTLS
+		case de.viaProxy:
+			kind = KindProxy
+		}
+	}
+	return &APIError{Kind: kind, Endpoint: endpointOf(req), Err: err}
+}
+
+// classifyStatus определяет вид ошибки по статусу и телу.
+// 403 с JSON — это отказ API (токен, сессия); 403 с HTML или пустым телом — заглушка Qrator.
+
+func classifyStatus(resp *http.Response, body []byte) ErrorKind {
+	switch code := resp.StatusCode; {
+	case code == http.StatusUnauthorized:
+		return KindUnauthorized
+	case code == http.StatusForbidden:
+		if isJSON(resp.Header) && !strings.Contains(strings.ToLower(string(body)), "qrator") {
+			return KindUnauthorized
+		}
+		return KindAntiBot
+	case code == http.StatusTooManyRequests:
+		return KindRateLimited
+	case code >= 500:
+		return KindServer
+	case code >= 400:
+		return KindBadRequest
+	default:
+		return KindUnknown
+	}
+}
+
+func isJSON(h http.Header) bool {
+	mt, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && (mt == "application/json" || strings.HasSuffix(mt, "+json"))
+}
+
+func requestID(h http.Header) string {
+	for _, k := range []string{"X-Request-Id", "X-Trace-Id", "Request-Id", "X-Correlation-Id"} {
+		if v := h.Get(k); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func endpointOf(req *http.Request) string {
+	if req == nil || req.URL == nil {
+		return ""
+	}
+	return req.URL.Path
+}
+
+// truncateBody обрезает тело до maxErrorBody байт, не разрывая UTF-8 символ.
+func truncateBody(body []byte) string {
+	if len(body) <= maxErrorBody {
+		return string(body)
+	}
+	cut := maxErrorBody
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return string(body[:cut]) + "…"
+}
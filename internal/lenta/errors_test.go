@@ -0,0 +1,113 @@
+package lenta
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewStatusError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		kind        ErrorKind
+		sentinel    error
+	}{
+		{"401", http.StatusUnauthorized, "application/json", `{}`, KindUnauthorized, ErrUnauthorized},
+		{"403 JSON", http.StatusForbidden, "application/json; charset=utf-8", `{"message":"token expired"}`, KindUnauthorized, ErrUnauthorized},
+		{"403 JSON от Qrator", http.StatusForbidden, "application/json", `{"source":"Qrator"}`, KindAntiBot, ErrAntiBot},
+		{"403 HTML", http.StatusForbidden, "text/html", `<html>доступ ограничен</html>`, KindAntiBot, ErrAntiBot},
+		{"403 пустой", http.StatusForbidden, "", ``, KindAntiBot, ErrAntiBot},
+		{"404", http.StatusNotFound, "application/json", `{}`, KindBadRequest, ErrBadRequest},
+		{"429", http.StatusTooManyRequests, "application/json", `{}`, KindRateLimited, ErrRateLimited},
+		{"500", http.StatusInternalServerError, "text/plain", `oops`, KindServer, ErrServer},
+		{"503", http.StatusServiceUnavailable, "text/html", ``, KindServer, ErrServer},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "https://lenta.com/api-gateway/v1/catalog/items", nil)
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Request: req}
+			if tt.contentType != "" {
+				resp.Header.Set("Content-Type", tt.contentType)
+			}
+			resp.Header.Set("Retry-After", "5")
+
+			var err error = newStatusError(resp, []byte(tt.body))
+			if k := KindOf(err); k != tt.kind {
+				t.Fatalf("вид %s, ожидался %s", k, tt.kind)
+			}
+			wrapped := fmt.Errorf("категория 128: %w", err)
+			if !errors.Is(wrapped, tt.sentinel) || KindOf(wrapped) != tt.kind {
+				t.Errorf("errors.Is(%v, %v) = false", wrapped, tt.sentinel)
+			}
+			for _, other := range kindSentinels {
+				if other != tt.sentinel && errors.Is(err, other) {
+					t.Errorf("ошибка совпала и с %v", other)
+				}
+			}
+
+			apiErr := err.(*APIError)
+			if apiErr.StatusCode != tt.status || apiErr.Endpoint != "/api-gateway/v1/catalog/items" {
+				t.Errorf("APIError = %+v", apiErr)
+			}
+			wantRetryAfter := time.Duration(0)
+			if tt.status == http.StatusTooManyRequests || tt.status == http.StatusServiceUnavailable {
+				wantRetryAfter = 5 * time.Second
+			}
+			if apiErr.RetryAfter != wantRetryAfter {
+				t.Errorf("RetryAfter = %s, ожидалось %s", apiErr.RetryAfter, wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestNewDecodeError(t *testing.T) {
+	req := httptest.NewRequest("POST", "https://lenta.com/api-gateway/v1/catalog/items", nil)
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Request: req}
+	body := strings.Repeat("x", 2*maxErrorBody)
+
+	err := newDecodeError(resp, []byte(body), io.ErrUnexpectedEOF)
+	if !errors.Is(err, ErrDecode) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("errors.Is не видит ErrDecode или исходную ошибку: %v", err)
+	}
+	if len(err.Body) > maxErrorBody+len("…") {
+		t.Errorf("тело ошибки не обрезано: %d байт", len(err.Body))
+	}
+}
+
+func TestNewTransportError(t *testing.T) {
+	req := httptest.NewRequest("GET", "https://lenta.com/api-gateway/v1/stores", nil)
+	refused := errors.New("connection refused")
+	tests := []struct {
+		name     string
+		err      error
+		kind     ErrorKind
+		sentinel error
+	}{
+		{"сеть", refused, KindNetwork, ErrNetwork},
+		{"dial напрямую", &dialError{stage: "connect", err: refused}, KindNetwork, ErrNetwork},
+		{"прокси", &dialError{stage: "connect", viaProxy: true, err: refused}, KindProxy, ErrProxy},
+		{"TLS через прокси", &dialError{stage: "tls", viaProxy: true, err: refused}, KindTLS, ErrTLS},
+		{"общий dial прокси", sharedDialError(&dialError{stage: "connect", viaProxy: true, err: refused}), KindProxy, ErrProxy},
+	}
+	for _, tt := range tests {
+		err := newTransportError(req, fmt.Errorf("round trip: %w", tt.err))
+		if err.Kind != tt.kind || !errors.Is(err, tt.sentinel) || !errors.Is(err, refused) {
+			t.Errorf("%s: %v (вид %s), ожидался %s", tt.name, err, err.Kind, tt.kind)
+		}
+		if err.StatusCode != 0 || err.Endpoint != "/api-gateway/v1/stores" {
+			t.Errorf("%s: %+v", tt.name, err)
+		}
+	}
+
+	if KindOf(refused) != KindUnknown || KindOf(nil) != KindUnknown {
+		t.Error("KindOf обычной ошибки не KindUnknown")
+	}
+}
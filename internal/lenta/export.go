@@ -1,42 +1,247 @@
 package lenta
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 )
 
-// ExportToCSV сохраняет товары в CSV.
-// Используется разделитель ';' (совместимость с RU Excel).
+// ExportFormat — формат файла выгрузки.
+
+type ExportFormat string
+
+const (
+	FormatCSV   ExportFormat = "csv"
+	FormatJSON  ExportFormat = "json"  // массив с отступами
+	FormatJSONL ExportFormat = "jsonl" // один товар на строку, пишется потоково
+)
+
+// ParseExportFormat разбирает формат из флага CLI.
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch f := ExportFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case FormatCSV, FormatJSON, FormatJSONL:
+		return f, nil
+	case "ndjson":
+		return FormatJSONL, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат выгрузки: %q (csv, json, jsonl)", s)
+	}
+}
+
+// FormatFromPath определяет формат по расширению файла; неизвестное — csv.
+func FormatFromPath(path string) ExportFormat {
+	if f, err := ParseExportFormat(strings.TrimPrefix(filepath.Ext(path), ".")); err == nil {
+		return f
+	}
+	return FormatCSV
+}
+
+// ExportOptions — параметры выгрузки.
+
+type ExportOptions struct {
+	Format ExportFormat // "" — по расширению пути
+
+	// Raw — в JSON и JSONL писать исходный JSON позиции (Product.Raw),
+	// включая поля, которых нет в модели. На CSV не влияет.
+	Raw bool
+
+	// Full — CSV со всеми полями товара (productCSVHeader).
+	// false — краткий CSV name;price;url. JSON и JSONL всегда полные.
+	Full bool
+}
+
+// Exporter — потоковая выгрузка товаров: Write на каждый товар, Close в конце.
+// До Close файл может быть неполным.
+
+type Exporter interface {
+	Write(p *Product) error
+	Close() error
+}
+
+// NewExporter создаёт файл path и возвращает выгрузку в нужном формате.
 // Файл перезаписывается, если существует.
 
-func ExportToCSV(products []ProductExport, path string) error {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil && path != "" {
-		return err
+func NewExporter(path string, opts ExportOptions) (Exporter, error) {
+	format := opts.Format
+	if format == "" {
+		format = FormatFromPath(path)
 	}
 
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
 	f, err := os.Create(path)
 	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatJSON:
+		return &jsonExporter{f: f, w: bufio.NewWriter(f), raw: opts.Raw}, nil
+	case FormatJSONL:
+		return &jsonlExporter{f: f, w: bufio.NewWriter(f), raw: opts.Raw}, nil
+	default:
+		e := &csvExporter{f: f, w: csv.NewWriter(f), full: opts.Full}
+		e.w.Comma = ';'
+		header := shortCSVHeader
+		if e.full {
+			header = productCSVHeader
+		}
+		if err := e.w.Write(header); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return e, nil
+	}
+}
+
+// shortCSVHeader — краткий CSV по умолчанию. Цена в рублях.
+var shortCSVHeader = []string{"name", "price", "url"}
+
+// productCSVHeader — колонки CSV с полным товаром (ExportOptions.Full). Цены в рублях.
+var productCSVHeader = []string{
+	"id", "name", "price", "price_regular", "discount", "brand", "country",
+	"unit", "package", "available", "rating", "votes", "promo_to", "category", "store_id", "url",
+}
+
+type csvExporter struct {
+	f    *os.File
+	w    *csv.Writer
+	full bool
+}
+
+func (e *csvExporter) Write(p *Product) error {
+	if !e.full {
+		return e.w.Write([]string{p.Name, fmtPrice(float64(p.Prices.Price) / 100), p.URL()})
+	}
+
+	var discount string
+	if len(p.Badges.Discount) > 0 {
+		discount = p.Badges.Discount[0].Title
+	}
+	var promoTo string
+	if p.Promo != nil {
+		promoTo = p.Promo.DateTo
+	}
+	return e.w.Write([]string{
+		strconv.Itoa(p.ID),
+		p.Name,
+		fmtKopecks(p.Prices.Price),
+		fmtKopecks(p.Prices.PriceRegular),
+		discount,
+		p.Brand.Name,
+		p.Country,
+		p.UnitOfMeasure,
+		p.Weight.Package,
+		strconv.FormatBool(p.Stock.IsAvailable),
+		strconv.FormatFloat(p.Rating.Rate, 'f', -1, 64),
+		strconv.Itoa(p.Rating.Votes),
+		promoTo,
+		strings.Join(p.CategoryPath(), " / "),
+		strconv.Itoa(p.StoreID),
+		p.URL(),
+	})
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	if err := e.w.Error(); err != nil {
+		e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
+
+// jsonExporter пишет массив потоково: "[", элементы через запятую, "]" в Close.
+type jsonExporter struct {
+	f   *os.File
+	w   *bufio.Writer
+	raw bool
+	n   int
+}
+
+func (e *jsonExporter) Write(p *Product) error {
+	data, err := productJSON(p, e.raw)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "  ", "  "); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	w := csv.NewWriter(f)
-	w.Comma = ';'
+	sep := ",\n  "
+	if e.n == 0 {
+		sep = "[\n  "
+	}
+	e.n++
+	if _, err := e.w.WriteString(sep); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(e.w)
+	return err
+}
 
-	if err := w.Write([]string{"name", "price", "url"}); err != nil {
+func (e *jsonExporter) Close() error {
+	tail := "\n]\n"
+	if e.n == 0 {
+		tail = "[]\n"
+	}
+	if _, err := e.w.WriteString(tail); err != nil {
+		e.f.Close()
 		return err
 	}
+	if err := e.w.Flush(); err != nil {
+		e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
 
-	for _, p := range products {
-		if err := w.Write([]string{p.Name, fmtPrice(p.Price), p.URL}); err != nil {
-			return err
-		}
+// jsonlExporter пишет по товару на строку и сбрасывает буфер после каждого —
+// прерванный обход оставляет валидный файл.
+type jsonlExporter struct {
+	f   *os.File
+	w   *bufio.Writer
+	raw bool
+}
+
+func (e *jsonlExporter) Write(p *Product) error {
+	data, err := productJSON(p, e.raw)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return err
 	}
+	buf.WriteByte('\n')
+	if _, err := buf.WriteTo(e.w); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *jsonlExporter) Close() error {
+	if err := e.w.Flush(); err != nil {
+		e.f.Close()
+		return err
+	}
+	return e.f.Close()
+}
 
-	w.Flush()
-	return w.Error()
+// productJSON — JSON товара: модель или исходная позиция API.
+func productJSON(p *Product, raw bool) ([]byte, error) {
+	if raw && len(p.Raw) > 0 {
+		return p.Raw, nil
+	}
+	return json.Marshal(p)
 }
 
 func fmtPrice(rub float64) string {
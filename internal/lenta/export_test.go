@@ -0,0 +1,84 @@
+package lenta
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testProducts() []Product {
+	return []Product{
+		{ID: 1, Name: "Молоко", Slug: "moloko-1", StoreID: 7, Prices: Prices{Price: 8990, PriceRegular: 9990}},
+		{ID: 2, Name: "Хлеб; ржаной", Slug: "hleb-2", StoreID: 7, Prices: Prices{Price: 4500}},
+	}
+}
+
+func exportAll(t *testing.T, path string, opts ExportOptions) string {
+	t.Helper()
+	e, err := NewExporter(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range testProducts() {
+		if err := e.Write(&p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExportCSVDefaultIsShort(t *testing.T) {
+	got := exportAll(t, filepath.Join(t.TempDir(), "products.csv"), ExportOptions{})
+	want := "name;price;url\n" +
+		"Молоко;89.90;https://lenta.com/p/moloko-1\n" +
+		"\"Хлеб; ржаной\";45.00;https://lenta.com/p/hleb-2\n"
+	if got != want {
+		t.Errorf("краткий CSV:\n%s\nожидался\n%s", got, want)
+	}
+}
+
+func TestExportFullRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"products.csv", "products.json", "products.jsonl"} {
+		path := filepath.Join(dir, name)
+		out := exportAll(t, path, ExportOptions{Full: true})
+
+		switch filepath.Ext(name) {
+		case ".csv":
+			if !strings.HasPrefix(out, strings.Join(productCSVHeader, ";")+"\n") {
+				t.Errorf("%s: нет полного заголовка", name)
+			}
+		case ".json":
+			var items []Product
+			if err := json.Unmarshal([]byte(out), &items); err != nil || len(items) != 2 {
+				t.Errorf("%s: невалидный массив (%d, %v)", name, len(items), err)
+			}
+		}
+
+		snap, err := LoadSnapshot(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		rec, ok := snap[PriceKey{ProductID: 1, StoreID: 7}]
+		if len(snap) != 2 || !ok || rec.Price != 8990 || rec.PriceRegular != 9990 {
+			t.Errorf("%s: снимок %+v", name, snap)
+		}
+	}
+}
+
+func TestLoadSnapshotShortCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.csv")
+	exportAll(t, path, ExportOptions{})
+	if _, err := LoadSnapshot(path); err == nil {
+		t.Error("краткий CSV без id должен давать ошибку")
+	}
+}
@@ -0,0 +1,294 @@
+package lenta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// Порядок заголовков HTTP/2 — часть fingerprint браузера, но x/net/http2
+// кодирует req.Header в порядке обхода map, то есть случайно.
+// headerOrderConn стоит между http2.ClientConn и uTLS-соединением:
+// перехватывает исходящие HEADERS, раскодирует HPACK-блок, переставляет поля
+// по BrowserProfile.HeaderOrder и кодирует заново своим энкодером.
+// Сервер видит только поток нашего энкодера, поэтому динамическая таблица
+// HPACK у него согласована. Остальные фреймы проходят без изменений.
+
+const (
+	frameHeaderLen = 9
+	// minMaxFrameSize — минимальный SETTINGS_MAX_FRAME_SIZE: такие фреймы
+	// примет любой сервер, отслеживать его настройки не нужно.
+	minMaxFrameSize = 16384
+
+	flagEndStream  = 0x1
+	flagEndHeaders = 0x4
+	flagPadded     = 0x8
+	flagPriority   = 0x20
+
+	settingHeaderTableSize = 0x1
+)
+
+type headerOrderConn struct {
+	net.Conn
+	rank map[string]int
+
+	wmu      sync.Mutex
+	preface  int          // сколько байт client preface уже передано
+	pending  []byte       // неполный фрейм из прошлого Write
+	block    bytes.Buffer // HPACK-блок HEADERS, ожидающий CONTINUATION
+	blockHdr []byte       // заголовок и priority-поля этого HEADERS
+	dec      *hpack.Decoder
+	out      bytes.Buffer
+
+	emu  sync.Mutex // энкодер меняют Write и (через SETTINGS сервера) Read
+	enc  *hpack.Encoder
+	hbuf bytes.Buffer
+
+	rmu   sync.Mutex
+	rhead []byte // заголовок входящего фрейма, пока он не прочитан целиком
+	rskip int    // байт полезной нагрузки, которые нужно пропустить
+	rset  []byte // полезная нагрузка входящего SETTINGS
+	rleft int    // сколько байт SETTINGS ещё не прочитано
+}
+
+// newHeaderOrderConn оборачивает conn, если у профиля задан порядок заголовков.
+
+func newHeaderOrderConn(conn net.Conn, order []string) net.Conn {
+	if len(order) == 0 {
+		return conn
+	}
+	c := &headerOrderConn{Conn: conn, rank: make(map[string]int, len(order))}
+	for i, name := range order {
+		c.rank[strings.ToLower(name)] = i
+	}
+	c.dec = hpack.NewDecoder(4096, nil)
+	c.enc = hpack.NewEncoder(&c.hbuf)
+	return c
+}
+
+func (c *headerOrderConn) Write(p []byte) (int, error) {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	n := len(p)
+	if c.preface < len(http2.ClientPreface) {
+		k := min(len(http2.ClientPreface)-c.preface, len(p))
+		c.out.Write(p[:k])
+		c.preface += k
+		p = p[k:]
+	}
+
+	data := append(c.pending, p...)
+	for len(data) >= frameHeaderLen {
+		length := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+		if len(data) < frameHeaderLen+length {
+			break
+		}
+		if err := c.frame(data[:frameHeaderLen+length]); err != nil {
+			return 0, err
+		}
+		data = data[frameHeaderLen+length:]
+	}
+	c.pending = append(c.pending[:0:0], data...)
+
+	if c.out.Len() > 0 {
+		_, err := c.Conn.Write(c.out.Bytes())
+		c.out.Reset()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// frame обрабатывает один исходящий фрейм целиком.
+func (c *headerOrderConn) frame(f []byte) error {
+	typ, flags := http2.FrameType(f[3]), f[4]
+	payload := f[frameHeaderLen:]
+
+	switch typ {
+	case http2.FrameHeaders:
+		if flags&flagPadded != 0 {
+			if len(payload) < 1 || int(payload[0]) >= len(payload) {
+				return fmt.Errorf("http2: неверный padding в HEADERS")
+			}
+			payload = payload[1 : len(payload)-int(payload[0])]
+		}
+		hdr := append([]byte(nil), f[:frameHeaderLen]...)
+		hdr[4] = flags &^ flagPadded
+		if flags&flagPriority != 0 {
+			if len(payload) < 5 {
+				return fmt.Errorf("http2: неверный priority в HEADERS")
+			}
+			hdr, payload = append(hdr, payload[:5]...), payload[5:]
+		}
+		c.blockHdr = hdr
+		c.block.Reset()
+		c.block.Write(payload)
+		if flags&flagEndHeaders != 0 {
+			return c.flushHeaders()
+		}
+		return nil
+
+	case http2.FrameContinuation:
+		if c.blockHdr == nil {
+			break
+		}
+		c.block.Write(payload)
+		if flags&flagEndHeaders != 0 {
+			return c.flushHeaders()
+		}
+		return nil
+	}
+
+	c.out.Write(f)
+	return nil
+}
+
+// flushHeaders переставляет поля собранного HPACK-блока и пишет HEADERS
+// (и CONTINUATION, если блок не влез в один фрейм).
+
+func (c *headerOrderConn) flushHeaders() error {
+	fields, err := c.dec.DecodeFull(c.block.Bytes())
+	if err != nil {
+		return fmt.Errorf("http2: HPACK исходящего запроса: %w", err)
+	}
+	c.sortFields(fields)
+
+	c.emu.Lock()
+	c.hbuf.Reset()
+	for _, hf := range fields {
+		c.enc.WriteField(hf)
+	}
+	block := append([]byte(nil), c.hbuf.Bytes()...)
+	c.emu.Unlock()
+
+	hdr, streamID := c.blockHdr, binary.BigEndian.Uint32(c.blockHdr[5:9])
+	prio := hdr[frameHeaderLen:]
+	flags := hdr[4] &^ flagEndHeaders
+
+	first := true
+	for first || len(block) > 0 {
+		room := minMaxFrameSize
+		if first {
+			room -= len(prio)
+		}
+		chunk := block[:min(room, len(block))]
+		block = block[len(chunk):]
+
+		typ, fl := http2.FrameContinuation, byte(0)
+		var extra []byte
+		if first {
+			typ, fl, extra = http2.FrameHeaders, flags, prio
+		}
+		if len(block) == 0 {
+			fl |= flagEndHeaders
+		}
+		length := len(extra) + len(chunk)
+		c.out.Write([]byte{byte(length >> 16), byte(length >> 8), byte(length), byte(typ), fl})
+		binary.Write(&c.out, binary.BigEndian, streamID)
+		c.out.Write(extra)
+		c.out.Write(chunk)
+		first = false
+	}
+
+	c.blockHdr = nil
+	c.block.Reset()
+	return nil
+}
+
+// sortFields упорядочивает поля по профилю. Псевдозаголовки всегда идут первыми
+// (RFC 9113), поля вне HeaderOrder — после известных, в алфавитном порядке.
+
+func (c *headerOrderConn) sortFields(fields []hpack.HeaderField) {
+	key := func(hf hpack.HeaderField) (bool, int) {
+		r, ok := c.rank[hf.Name]
+		if !ok {
+			r = len(c.rank)
+		}
+		return !strings.HasPrefix(hf.Name, ":"), r
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		ri, ki := key(fields[i])
+		rj, kj := key(fields[j])
+		switch {
+		case ri != rj:
+			return !ri
+		case ki != kj:
+			return ki < kj
+		case ki == len(c.rank):
+			return fields[i].Name < fields[j].Name
+		}
+		return false
+	})
+}
+
+// Read пропускает входящие данные без изменений, попутно разбирая SETTINGS
+// сервера: SETTINGS_HEADER_TABLE_SIZE ограничивает таблицу нашего энкодера.
+
+func (c *headerOrderConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.rmu.Lock()
+		c.scan(p[:n])
+		c.rmu.Unlock()
+	}
+	return n, err
+}
+
+func (c *headerOrderConn) scan(data []byte) {
+	for len(data) > 0 {
+		switch {
+		case c.rleft > 0:
+			k := min(c.rleft, len(data))
+			c.rset = append(c.rset, data[:k]...)
+			c.rleft -= k
+			data = data[k:]
+			if c.rleft == 0 {
+				c.applySettings(c.rset)
+				c.rset = c.rset[:0]
+			}
+		case c.rskip > 0:
+			k := min(c.rskip, len(data))
+			c.rskip -= k
+			data = data[k:]
+		default:
+			k := min(frameHeaderLen-len(c.rhead), len(data))
+			c.rhead = append(c.rhead, data[:k]...)
+			data = data[k:]
+			if len(c.rhead) < frameHeaderLen {
+				return
+			}
+			length := int(c.rhead[0])<<16 | int(c.rhead[1])<<8 | int(c.rhead[2])
+			isSettings := http2.FrameType(c.rhead[3]) == http2.FrameSettings && c.rhead[4]&0x1 == 0
+			c.rhead = c.rhead[:0]
+			if isSettings {
+				c.rleft = length
+				if length == 0 {
+					continue
+				}
+			} else {
+				c.rskip = length
+			}
+		}
+	}
+}
+
+func (c *headerOrderConn) applySettings(payload []byte) {
+	for len(payload) >= 6 {
+		id, val := binary.BigEndian.Uint16(payload), binary.BigEndian.Uint32(payload[2:])
+		payload = payload[6:]
+		if id == settingHeaderTableSize {
+			c.emu.Lock()
+			c.enc.SetMaxDynamicTableSizeLimit(val)
+			c.emu.Unlock()
+		}
+	}
+}
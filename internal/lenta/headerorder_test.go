@@ -0,0 +1,144 @@
+package lenta
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// recordConn запоминает всё, что сервер прочитал из соединения.
+type recordConn struct {
+	net.Conn
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (c *recordConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.mu.Lock()
+	c.buf.Write(p[:n])
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *recordConn) bytes() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.buf.Bytes()...)
+}
+
+// wireHeaders разбирает записанный поток клиента и возвращает имена полей
+// каждого HEADERS в порядке на проводе.
+func wireHeaders(t *testing.T, data []byte) [][]string {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte(http2.ClientPreface)) {
+		t.Fatal("нет client preface")
+	}
+	fr := http2.NewFramer(nil, bytes.NewReader(data[len(http2.ClientPreface):]))
+	fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	fr.MaxHeaderListSize = 1 << 20
+
+	var out [][]string
+	for {
+		f, err := fr.ReadFrame()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return out
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mh, ok := f.(*http2.MetaHeadersFrame); ok {
+			var names []string
+			for _, hf := range mh.Fields {
+				names = append(names, hf.Name)
+			}
+			out = append(out, names)
+		}
+	}
+}
+
+func TestHeaderOrderConn(t *testing.T) {
+	order := []string{":method", ":path", ":authority", ":scheme", "x-second", "user-agent", "x-first", "x-big"}
+
+	clientSide, serverSide := net.Pipe()
+	rec := &recordConn{Conn: serverSide}
+	var got []http.Header
+	var mu sync.Mutex
+	go (&http2.Server{}).ServeConn(rec, &http2.ServeConnOpts{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			got = append(got, r.Header.Clone())
+			mu.Unlock()
+			io.WriteString(w, "ok")
+		}),
+	})
+
+	cc, err := (&http2.Transport{}).NewClientConn(newHeaderOrderConn(clientSide, order))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	// Третий запрос с заголовком больше фрейма проверяет CONTINUATION,
+	// повторы — согласованность динамической таблицы HPACK.
+	big := strings.Repeat("0123456789abcdef", 2048)
+	for i := range 3 {
+		req, _ := http.NewRequest("GET", "https://lenta.com/api/test", nil)
+		req.Header.Set("X-First", "1")
+		req.Header.Set("User-Agent", "test-agent")
+		req.Header.Set("X-Second", "2")
+		req.Header.Set("X-Unlisted-B", "b")
+		req.Header.Set("X-Unlisted-A", "a")
+		if i == 2 {
+			req.Header.Set("X-Big", big)
+		}
+		resp, err := cc.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("запрос %d: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "ok" {
+			t.Fatalf("запрос %d: ответ %q", i, body)
+		}
+	}
+
+	mu.Lock()
+	if len(got) != 3 || got[2].Get("X-Big") != big || got[1].Get("X-First") != "1" {
+		t.Fatalf("сервер получил искажённые заголовки: %v", got)
+	}
+	mu.Unlock()
+
+	blocks := wireHeaders(t, rec.bytes())
+	if len(blocks) != 3 {
+		t.Fatalf("HEADERS на проводе: %d, ожидалось 3", len(blocks))
+	}
+	for i, names := range blocks {
+		want := []string{":method", ":path", ":authority", ":scheme", "x-second", "user-agent", "x-first"}
+		if i == 2 {
+			want = append(want, "x-big")
+		}
+		// accept-encoding добавляет сам x/net/http2 — его в списке нет.
+		want = append(want, "accept-encoding", "x-unlisted-a", "x-unlisted-b")
+		if strings.Join(names, ",") != strings.Join(want, ",") {
+			t.Errorf("запрос %d: порядок\n%v\nожидался\n%v", i, names, want)
+		}
+	}
+}
+
+func TestHeaderOrderConnDisabled(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	if newHeaderOrderConn(a, nil) != a {
+		t.Error("без HeaderOrder соединение должно оставаться как есть")
+	}
+}
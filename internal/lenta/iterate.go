@@ -0,0 +1,123 @@
+package lenta
+
+import (
+	"context"
+	"iter"
+	"log"
+)
+
+const (
+	defaultPageSize = 40
+	defaultMaxPages = 1000
+)
+
+// IterateOptions — параметры обхода товаров категории.
+
+type IterateOptions struct {
+	PageSize    int // размер страницы, 0 — 40
+	StartOffset int // продолжить обход с этого offset
+	MaxItems    int // остановиться после N уникальных товаров, 0 — без ограничения
+	MaxPages    int // защита от бесконечной пагинации, 0 — 1000
+
+	// Query — фильтры и сортировка (nil — без фильтров, popular).
+	Query *CatalogQuery
+
+	// Seen — ID товаров, уже выданных до StartOffset при продолжении обхода:
+	// они не выдаются повторно. Не изменяется.
+	Seen map[int]bool
+
+	// OnPage вызывается после каждой полученной страницы (до выдачи её товаров).
+	// NextOffset — откуда продолжать, если обход прервётся после этой страницы.
+	OnPage func(p PageInfo)
+}
+
+// PageInfo — сведения об обработанной странице.
+
+type PageInfo struct {
+	CategoryID int
+	Offset     int
+	NextOffset int
+	Items      int // товаров на странице
+	New        int // из них ещё не встречавшихся
+	Total      int // общее число товаров, если API его вернул (иначе 0)
+}
+
+// IterateCategory обходит все товары категории постранично.
+//
+// Обход останавливается, когда:
+//   - страница короче PageSize или пуста;
+//   - offset достиг Total (если API вернул общее число);
+//   - страница целиком состоит из уже выданных товаров (API игнорирует offset);
+//   - достигнуты MaxItems или MaxPages.
+//
+// Товары, повторяющиеся на разных страницах, выдаются один раз.
+// Ошибка запроса выдаётся вторым значением, после неё обход завершается.
+
+func IterateCategory(ctx context.Context, client *Client, categoryID int, opts IterateOptions) iter.Seq2[Product, error] {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = defaultMaxPages
+	}
+
+	return func(yield func(Product, error) bool) {
+		seen := make(map[int]bool, len(opts.Seen))
+		for id := range opts.Seen {
+			seen[id] = true
+		}
+		offset := opts.StartOffset
+		emitted := 0
+
+		for page := 0; page < opts.MaxPages; page++ {
+			data, err := FetchCatalogContext(ctx, client, categoryID, opts.Query, offset, opts.PageSize)
+			if err != nil {
+				yield(Product{}, err)
+				return
+			}
+
+			var fresh []Product
+			for _, item := range data.Items {
+				if seen[item.ID] {
+					continue
+				}
+				seen[item.ID] = true
+				fresh = append(fresh, item)
+			}
+
+			next := offset + len(data.Items)
+			if opts.OnPage != nil {
+				opts.OnPage(PageInfo{
+					CategoryID: categoryID,
+					Offset:     offset,
+					NextOffset: next,
+					Items:      len(data.Items),
+					New:        len(fresh),
+					Total:      data.Total,
+				})
+			}
+
+			for _, item := range fresh {
+				if !yield(item, nil) {
+					return
+				}
+				emitted++
+				if opts.MaxItems > 0 && emitted >= opts.MaxItems {
+					return
+				}
+			}
+
+			switch {
+			case len(data.Items) < opts.PageSize:
+				return
+			case data.Total > 0 && next >= data.Total:
+				return
+			case len(fresh) == 0:
+				log.Printf("[WARN] Категория %d: страница offset %d состоит из повторов — пагинация остановлена", categoryID, offset)
+				return
+			}
+			offset = next
+		}
+		log.Printf("[WARN] Категория %d: достигнут лимит %d страниц", categoryID, opts.MaxPages)
+	}
+}
@@ -0,0 +1,90 @@
+package lenta
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// sessionJar — cookiejar, который дополнительно помнит полные cookies
+// (domain, path, expires). Стандартный Jar.Cookies отдаёт только name=value,
+// а для сохранения сессии на диск нужны все атрибуты.
+
+type sessionJar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	cookies map[string]*http.Cookie // name|domain|path → cookie
+}
+
+func newSessionJar() (*sessionJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionJar{jar: jar, cookies: make(map[string]*http.Cookie)}, nil
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	for _, c := range cookies {
+		ck := *c
+		if ck.Domain == "" {
+			ck.Domain = u.Hostname()
+		}
+		if ck.Path == "" {
+			ck.Path = "/"
+		}
+		if ck.MaxAge > 0 {
+			ck.Expires = now.Add(time.Duration(ck.MaxAge) * time.Second)
+			ck.MaxAge = 0
+		}
+
+		key := ck.Name + "|" + ck.Domain + "|" + ck.Path
+		if c.MaxAge < 0 || (!ck.Expires.IsZero() && !ck.Expires.After(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = &ck
+	}
+}
+
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// Snapshot возвращает копии всех неистёкших cookies.
+func (j *sessionJar) Snapshot() []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	out := make([]*http.Cookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		ck := *c
+		out = append(out, &ck)
+	}
+	return out
+}
+
+// Reset очищает jar — используется перед применением новой сессии,
+// чтобы куки старой сессии не смешивались с новыми.
+func (j *sessionJar) Reset() {
+	jar, _ := cookiejar.New(nil)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar = jar
+	j.cookies = make(map[string]*http.Cookie)
+}
@@ -1,27 +1,56 @@
 package lenta
 
+import "encoding/json"
+
 // Модели соответствуют JSON-ответу catalog API.
 // Структура может измениться при обновлении frontend-а сайта.
-// Поля добавляются по мере необходимости.
+// Поля, которых нет в моделях, доступны экспортерам через Product.Raw.
 
 type CatalogItemsResponse struct {
 	Categories []Category `json:"categories,omitempty"`
 	Filters    Filters    `json:"filters,omitempty"`
 	Items      []Product  `json:"items"`
-	// Total может быть где-то в meta или отдельно — добавь если увидишь в полном ответе
+	Total      int        `json:"total,omitempty"` // общее число товаров; не во всех ответах
 }
 
 type Category struct {
 	ID          int    `json:"id"`
 	Name        string `json:"name,omitempty"`
 	Slug        string `json:"slug,omitempty"`
+	ParentID    int    `json:"parentId,omitempty"` // 0 — категория верхнего уровня
 	HasChildren bool   `json:"hasChildren"`
-	// ...
 }
 
+// Filters — фасеты, доступные в категории: что можно передать в CatalogQuery.
+
 type Filters struct {
-	Checkbox []interface{} `json:"checkbox"`
-	// ...
+	Range         []RangeFilter    `json:"range,omitempty"`         // цена, вес
+	Checkbox      []CheckboxFilter `json:"checkbox,omitempty"`      // бренд, «только со скидкой»
+	Multicheckbox []CheckboxFilter `json:"multicheckbox,omitempty"` // прочие фасеты с несколькими значениями
+}
+
+// RangeFilter — допустимый диапазон значений. Цена — в копейках.
+
+type RangeFilter struct {
+	Key  string  `json:"key"`
+	Name string  `json:"name,omitempty"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+// CheckboxFilter — фасет со списком значений.
+// Фильтр-переключатель (discount) приходит без Values.
+
+type CheckboxFilter struct {
+	Key    string        `json:"key"`
+	Name   string        `json:"name,omitempty"`
+	Values []FilterValue `json:"values,omitempty"`
+}
+
+type FilterValue struct {
+	Value string `json:"value"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count,omitempty"` // товаров с этим значением
 }
 
 type Product struct {
@@ -34,7 +63,82 @@ type Product struct {
 	Badges   Badges   `json:"badges,omitempty"`
 	Features Features `json:"features,omitempty"`
 	Weight   Weight   `json:"weight,omitempty"`
-	// Добавляй другие поля по мере необходимости
+
+	Brand         Brand      `json:"brand,omitempty"`
+	Country       string     `json:"countryOfOrigin,omitempty"` // "Россия"
+	Images        []Image    `json:"images,omitempty"`
+	UnitOfMeasure string     `json:"unitOfMeasure,omitempty"` // "шт", "кг"
+	QuantityStep  float64    `json:"quantityStep,omitempty"`  // шаг количества в корзине: 1 для штучных, 0.1 для весовых
+	Stock         Stock      `json:"stock,omitempty"`
+	Promo         *Promo     `json:"promo,omitempty"`
+	Categories    []Category `json:"categories,omitempty"` // путь от корня каталога до категории товара
+
+	// Raw — исходный JSON позиции, включая поля, не описанные в модели.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON разбирает позицию и сохраняет её исходный JSON в Raw.
+func (p *Product) UnmarshalJSON(data []byte) error {
+	type plain Product
+	if err := json.Unmarshal(data, (*plain)(p)); err != nil {
+		return err
+	}
+	p.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// URL — страница товара на сайте.
+func (p *Product) URL() string {
+	return baseURL + "/p/" + p.Slug
+}
+
+// CategoryPath возвращает имена категорий товара от корня.
+func (p *Product) CategoryPath() []string {
+	path := make([]string, 0, len(p.Categories))
+	for _, c := range p.Categories {
+		path = append(path, c.Name)
+	}
+	return path
+}
+
+type Brand struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"`
+}
+
+type Image struct {
+	Small  string `json:"small,omitempty"`
+	Medium string `json:"medium,omitempty"`
+	Large  string `json:"large,omitempty"`
+}
+
+// URL — самое крупное из доступных изображений.
+func (i Image) URL() string {
+	switch {
+	case i.Large != "":
+		return i.Large
+	case i.Medium != "":
+		return i.Medium
+	default:
+		return i.Small
+	}
+}
+
+// Stock — наличие в выбранном магазине.
+
+type Stock struct {
+	IsAvailable bool    `json:"isAvailable"`
+	Quantity    float64 `json:"quantity,omitempty"` // остаток в единицах UnitOfMeasure; не всегда приходит
+}
+
+// Promo — акция, по которой действует Prices.Price.
+// Даты приходят строками ISO 8601 (формат бывает с временем и без).
+
+type Promo struct {
+	Title    string `json:"title,omitempty"`
+	DateFrom string `json:"dateFrom,omitempty"`
+	DateTo   string `json:"dateTo,omitempty"`
 }
 
 type Prices struct {
@@ -43,7 +147,6 @@ type Prices struct {
 	Cost               int  `json:"cost"`
 	CostRegular        int  `json:"costRegular"`
 	IsLoyaltyCardPrice bool `json:"isLoyaltyCardPrice"`
-	// ...
 }
 
 type Rating struct {
@@ -53,29 +156,20 @@ type Rating struct {
 
 type Badges struct {
 	Discount []DiscountBadge `json:"discount,omitempty"`
-	// ...
 }
 
 type DiscountBadge struct {
 	Title string `json:"title"` // "-23%"
-	// ...
 }
 
 type Features struct {
 	IsAdult   bool   `json:"isAdult"`
 	IsAlcohol bool   `json:"isAlcohol"`
 	MarkType  string `json:"markType,omitempty"` // "MILK"
-	// ...
 }
 
 type Weight struct {
 	Gross   int    `json:"gross"`
+	Net     int    `json:"net,omitempty"`
 	Package string `json:"package"` // "900мл"
-	// ...
-}
-
-type ProductExport struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
-	URL   string  `json:"url"`
 }
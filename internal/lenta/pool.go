@@ -0,0 +1,292 @@
+package lenta
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"golang.org/x/net/http2"
+)
+
+const defaultIdleConnTimeout = 90 * time.Second
+
+// dialFunc открывает «сырое» TCP-соединение до addr (напрямую или через туннель прокси).
+// uTLS handshake и HTTP/2 поверх него делает пул.
+
+type dialFunc func(ctx context.Context, addr string) (net.Conn, error)
+
+// PoolStats — снимок состояния пула соединений.
+
+type PoolStats struct {
+	Open      int   // живых соединений в пуле
+	Active    int   // из них с активными стримами
+	Idle      int   // из них без стримов
+	Dials     int64 // всего установлено соединений (uTLS handshake)
+	Reuses    int64 // запросов, отправленных по уже открытому соединению
+	Evictions int64 // соединений закрыто как простаивающие или битые
+}
+
+// connPool хранит живые HTTP/2 соединения, сгруппированные по ключу «прокси + хост».
+// Один ClientConn мультиплексирует много запросов, поэтому handshake
+// выполняется только при открытии нового соединения — как в обычной вкладке Chrome.
+
+type connPool struct {
+	mu          sync.Mutex
+	conns       map[string][]*http2.ClientConn
+	dialing     map[string]*dialCall // соединения, которые открываются прямо сейчас
+	h2          *http2.Transport
+	idleTimeout time.Duration
+	helloID     utls.ClientHelloID
+	headerOrder []string
+	stats       PoolStats
+}
+
+func newConnPool(idleTimeout time.Duration, helloID utls.ClientHelloID, headerOrder []string) *connPool {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleConnTimeout
+	}
+	return &connPool{
+		conns:   make(map[string][]*http2.ClientConn),
+		dialing: make(map[string]*dialCall),
+		// ReadIdleTimeout включает PING-проверки: полуоткрытые соединения
+		// закрываются транспортом и вычищаются из пула при следующем get.
+		h2: &http2.Transport{
+			ReadIdleTimeout: 30 * time.Second,
+			PingTimeout:     15 * time.Second,
+		},
+		idleTimeout: idleTimeout,
+		helloID:     helloID,
+		headerOrder: headerOrder,
+	}
+}
+
+// roundTrip отправляет запрос по соединению из пула.
+// Если переиспользованное соединение оказалось мёртвым, запрос
+// один раз повторяется по свежему соединению (когда тело можно перечитать).
+
+func (p *connPool) roundTrip(req *http.Request, proxyKey string, dial dialFunc) (*http.Response, error) {
+	ctx := req.Context()
+	addr := targetAddr(req.URL)
+	key := proxyKey + "|" + addr
+
+	for attempt := 0; ; attempt++ {
+		cc, reused, err := p.get(ctx, key, req.URL.Hostname(), addr, proxyKey != "direct", dial)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := cc.RoundTrip(req.Clone(ctx))
+		if err == nil {
+			return resp, nil
+		}
+
+		if !cc.CanTakeNewRequest() {
+			p.remove(key, cc)
+		}
+		if !reused || attempt > 0 || ctx.Err() != nil {
+			return nil, err
+		}
+		retry, ok := rewindRequest(req)
+		if !ok {
+			return nil, err
+		}
+		req = retry
+	}
+}
+
+// dialCall — открытие соединения, результата которого ждут другие запросы.
+type dialCall struct {
+	done chan struct{}
+	err  error
+}
+
+// get возвращает соединение, способное принять новый запрос, либо открывает новое.
+// Одновременные запросы к одному ключу ждут одно открываемое соединение,
+// а не открывают каждый своё: N воркеров на старте — один handshake, как в браузере.
+
+func (p *connPool) get(ctx context.Context, key, hostname, addr string, viaProxy bool, dial dialFunc) (*http2.ClientConn, bool, error) {
+	for {
+		p.mu.Lock()
+		p.evictLocked()
+		for _, cc := range p.conns[key] {
+			if cc.CanTakeNewRequest() {
+				p.stats.Reuses++
+				p.mu.Unlock()
+				return cc, true, nil
+			}
+		}
+
+		call, ok := p.dialing[key]
+		if !ok {
+			break // p.mu остаётся захваченным — соединение открывает этот запрос
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		// Отмена чужого запроса не повод для ошибки — открываем сами.
+		if call.err != nil && !errors.Is(call.err, context.Canceled) && !errors.Is(call.err, context.DeadlineExceeded) {
+			return nil, false, sharedDialError(call.err)
+		}
+	}
+
+	call := &dialCall{done: make(chan struct{})}
+	p.dialing[key] = call
+	p.mu.Unlock()
+
+	cc, err := p.dial(ctx, hostname, addr, viaProxy, dial)
+
+	p.mu.Lock()
+	delete(p.dialing, key)
+	if err == nil {
+		p.conns[key] = append(p.conns[key], cc)
+		p.stats.Dials++
+	}
+	call.err = err
+	close(call.done)
+	p.mu.Unlock()
+
+	if err != nil {
+		return nil, false, err
+	}
+	return cc, false, nil
+}
+
+// dial выполняет TCP → uTLS handshake (ClientHello профиля браузера) → HTTP/2.
+// Заголовки запросов переставляются в порядок профиля (headerOrderConn).
+
+func (p *connPool) dial(ctx context.Context, hostname, addr string, viaProxy bool, dial dialFunc) (*http2.ClientConn, error) {
+	conn, err := dial(ctx, addr)
+	if err != nil {
+		return nil, &dialError{stage: "connect", viaProxy: viaProxy, err: err}
+	}
+
+	uConn := utls.UClient(conn, &utls.Config{ServerName: hostname}, p.helloID)
+	if err := uConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, &dialError{stage: "tls", viaProxy: viaProxy, err: err}
+	}
+
+	cc, err := p.h2.NewClientConn(newHeaderOrderConn(uConn, p.headerOrder))
+	if err != nil {
+		uConn.Close()
+		return nil, err
+	}
+	return cc, nil
+}
+
+// dialError — ошибка открытия соединения с указанием этапа:
+// connect (TCP / CONNECT к прокси) или tls (uTLS handshake).
+
+type dialError struct {
+	stage    string
+	viaProxy bool
+	shared   bool // ошибка чужого dial: прокси о ней уже сообщил открывавший запрос
+	err      error
+}
+
+func (e *dialError) Error() string { return e.stage + ": " + e.err.Error() }
+
+func (e *dialError) Unwrap() error { return e.err }
+
+// sharedDialError помечает ошибку dial для запросов, которые его ждали.
+func sharedDialError(err error) error {
+	var de *dialError
+	if !errors.As(err, &de) {
+		return err
+	}
+	shared := *de
+	shared.shared = true
+	return &shared
+}
+
+// evictLocked закрывает битые соединения и соединения, простаивающие дольше idleTimeout.
+
+func (p *connPool) evictLocked() {
+	now := time.Now()
+	for key, list := range p.conns {
+		kept := list[:0]
+		for _, cc := range list {
+			st := cc.State()
+			idleTooLong := st.StreamsActive == 0 && !st.LastIdle.IsZero() && now.Sub(st.LastIdle) > p.idleTimeout
+			if st.Closed || st.Closing || idleTooLong {
+				cc.Close()
+				p.stats.Evictions++
+				continue
+			}
+			kept = append(kept, cc)
+		}
+		if len(kept) == 0 {
+			delete(p.conns, key)
+		} else {
+			p.conns[key] = kept
+		}
+	}
+}
+
+func (p *connPool) remove(key string, target *http2.ClientConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := p.conns[key]
+	for i, cc := range list {
+		if cc == target {
+			p.conns[key] = append(list[:i], list[i+1:]...)
+			cc.Close()
+			p.stats.Evictions++
+			return
+		}
+	}
+}
+
+// Stats возвращает текущую статистику пула.
+
+func (p *connPool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.evictLocked()
+	st := p.stats
+	for _, list := range p.conns {
+		for _, cc := range list {
+			st.Open++
+			if cc.State().StreamsActive > 0 {
+				st.Active++
+			} else {
+				st.Idle++
+			}
+		}
+	}
+	return st
+}
+
+// Close закрывает все соединения пула.
+
+func (p *connPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, list := range p.conns {
+		for _, cc := range list {
+			cc.Close()
+		}
+		delete(p.conns, key)
+	}
+}
+
+// targetAddr возвращает host:port назначения (порт 443 по умолчанию).
+
+func targetAddr(u *url.URL) string {
+	if _, _, err := net.SplitHostPort(u.Host); err == nil {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), "443")
+}
@@ -0,0 +1,96 @@
+package lenta
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// TestConnPoolSharedDial — одновременные запросы к одному ключу
+// ждут один dial, а не открывают по соединению каждый.
+func TestConnPoolSharedDial(t *testing.T) {
+	p := newConnPool(0, utls.HelloChrome_131, nil)
+	defer p.Close()
+
+	var dials atomic.Int32
+	release := make(chan struct{})
+	refused := errors.New("connection refused")
+	dial := func(ctx context.Context, addr string) (net.Conn, error) {
+		dials.Add(1)
+		<-release
+		return nil, refused
+	}
+
+	const callers = 8
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, errs[i] = p.get(context.Background(), "proxy|lenta.com:443", "lenta.com", "lenta.com:443", true, dial)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond) // все запросы дошли до ожидания
+	close(release)
+	wg.Wait()
+
+	if n := dials.Load(); n != 1 {
+		t.Fatalf("dial вызван %d раз, ожидался 1", n)
+	}
+	owners := 0
+	for i, err := range errs {
+		var de *dialError
+		if !errors.As(err, &de) || !errors.Is(err, refused) {
+			t.Fatalf("запрос %d: err = %v", i, err)
+		}
+		if !de.shared {
+			owners++
+		}
+	}
+	// Ошибку прокси засчитывает только открывавший соединение запрос.
+	if owners != 1 {
+		t.Errorf("несобственных ошибок dial: %d из %d, ожидалась одна собственная", callers-owners, callers)
+	}
+}
+
+// TestConnPoolDialCanceledOwner — отмена запроса, открывающего соединение,
+// не роняет ожидающих: они открывают соединение сами.
+func TestConnPoolDialCanceledOwner(t *testing.T) {
+	p := newConnPool(0, utls.HelloChrome_131, nil)
+	defer p.Close()
+
+	started := make(chan struct{})
+	var dials atomic.Int32
+	dial := func(ctx context.Context, addr string) (net.Conn, error) {
+		if dials.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return nil, errors.New("второй dial")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go p.get(ctx, "k", "lenta.com", "lenta.com:443", false, dial)
+	<-started
+
+	done := make(chan error)
+	go func() {
+		_, _, err := p.get(context.Background(), "k", "lenta.com", "lenta.com:443", false, dial)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	err := <-done
+	if err == nil || err.Error() != "connect: второй dial" {
+		t.Fatalf("err = %v, ожидалась ошибка собственного dial", err)
+	}
+}
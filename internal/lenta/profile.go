@@ -0,0 +1,193 @@
+package lenta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// DefaultProfileName — профиль, используемый, если Config.Profile не задан.
+const DefaultProfileName = "chrome-131-win"
+
+// BrowserProfile связывает все признаки браузера, по которым anti-bot
+// сверяет клиента: TLS ClientHello, User-Agent, client hints и язык.
+// Один и тот же профиль применяется к uTLS-транспорту и к контексту Playwright —
+// расхождение (например, Chrome 131 в TLS и Chrome 143 в UA) само по себе повод для блокировки.
+
+type BrowserProfile struct {
+	Name    string
+	HelloID utls.ClientHelloID
+	Engine  string // движок Playwright: chromium, firefox, webkit
+
+	UserAgent       string
+	SecCHUA         string // пусто — браузер не шлёт client hints (Firefox, Safari)
+	SecCHUAMobile   string
+	SecCHUAPlatform string
+	AcceptLanguage  string
+	AcceptEncoding  string
+	Locale          string
+
+	ViewportWidth  int
+	ViewportHeight int
+	IsMobile       bool
+
+	// HeaderOrder — порядок заголовков HTTP/2 (в нижнем регистре, вместе
+	// с псевдозаголовками). Применяется в транспорте, см. headerOrderConn.
+	// Пусто — порядок x/net/http2 (случайный).
+	HeaderOrder []string
+}
+
+// Порядок заголовков fetch-запросов по движкам. Заголовки фронтенда
+// (sessiontoken, x-*) браузер шлёт в том порядке, в каком их выставил код,
+// поэтому они перечислены отдельным блоком.
+
+var (
+	chromeHeaderOrder = []string{
+		":method", ":authority", ":scheme", ":path",
+		"content-length", "sec-ch-ua-platform",
+		"client", "sessiontoken", "x-delivery-mode", "x-device-id", "x-domain",
+		"x-platform", "x-retail-brand", "x-store-id", "x-user-session-id",
+		"user-agent", "sec-ch-ua", "content-type", "sec-ch-ua-mobile", "accept",
+		"origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer",
+		"accept-encoding", "accept-language", "cookie", "priority",
+	}
+	firefoxHeaderOrder = []string{
+		":method", ":path", ":authority", ":scheme",
+		"user-agent", "accept", "accept-language", "accept-encoding", "referer", "content-type",
+		"client", "sessiontoken", "x-delivery-mode", "x-device-id", "x-domain",
+		"x-platform", "x-retail-brand", "x-store-id", "x-user-session-id",
+		"content-length", "origin", "cookie", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site",
+		"priority", "te",
+	}
+	safariHeaderOrder = []string{
+		":method", ":scheme", ":path", ":authority",
+		"content-type", "accept", "sec-fetch-site", "origin",
+		"client", "sessiontoken", "x-delivery-mode", "x-device-id", "x-domain",
+		"x-platform", "x-retail-brand", "x-store-id", "x-user-session-id",
+		"sec-fetch-mode", "user-agent", "referer", "sec-fetch-dest", "content-length",
+		"accept-language", "priority", "accept-encoding", "cookie",
+	}
+)
+
+var profiles = map[string]*BrowserProfile{
+	"chrome-131-win": {
+		Name:            "chrome-131-win",
+		HelloID:         utls.HelloChrome_131,
+		Engine:          "chromium",
+		UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+		SecCHUA:         `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
+		SecCHUAMobile:   "?0",
+		SecCHUAPlatform: `"Windows"`,
+		AcceptLanguage:  "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
+		AcceptEncoding:  "gzip, deflate, br, zstd",
+		Locale:          "ru-RU",
+		ViewportWidth:   1920,
+		ViewportHeight:  1080,
+		HeaderOrder:     chromeHeaderOrder,
+	},
+	"chrome-131-mac": {
+		Name:            "chrome-131-mac",
+		HelloID:         utls.HelloChrome_131,
+		Engine:          "chromium",
+		UserAgent:       "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+		SecCHUA:         `"Google Chrome";v="131", "Chromium";v="131", "Not_A Brand";v="24"`,
+		SecCHUAMobile:   "?0",
+		SecCHUAPlatform: `"macOS"`,
+		AcceptLanguage:  "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
+		AcceptEncoding:  "gzip, deflate, br, zstd",
+		Locale:          "ru-RU",
+		ViewportWidth:   1440,
+		ViewportHeight:  900,
+		HeaderOrder:     chromeHeaderOrder,
+	},
+	"chrome-133-win": {
+		Name:            "chrome-133-win",
+		HelloID:         utls.HelloChrome_133,
+		Engine:          "chromium",
+		UserAgent:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Safari/537.36",
+		SecCHUA:         `"Not(A:Brand";v="99", "Google Chrome";v="133", "Chromium";v="133"`,
+		SecCHUAMobile:   "?0",
+		SecCHUAPlatform: `"Windows"`,
+		AcceptLanguage:  "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
+		AcceptEncoding:  "gzip, deflate, br, zstd",
+		Locale:          "ru-RU",
+		ViewportWidth:   1920,
+		ViewportHeight:  1080,
+		HeaderOrder:     chromeHeaderOrder,
+	},
+	"firefox": {
+		Name:           "firefox",
+		HelloID:        utls.HelloFirefox_120,
+		Engine:         "firefox",
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:120.0) Gecko/20100101 Firefox/120.0",
+		AcceptLanguage: "ru-RU,ru;q=0.8,en-US;q=0.5,en;q=0.3",
+		AcceptEncoding: "gzip, deflate, br",
+		Locale:         "ru-RU",
+		ViewportWidth:  1920,
+		ViewportHeight: 1080,
+		HeaderOrder:    firefoxHeaderOrder,
+	},
+	"safari-mac": {
+		Name:           "safari-mac",
+		HelloID:        utls.HelloSafari_16_0,
+		Engine:         "webkit",
+		UserAgent:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Safari/605.1.15",
+		AcceptLanguage: "ru-RU,ru;q=0.9",
+		AcceptEncoding: "gzip, deflate, br",
+		Locale:         "ru-RU",
+		ViewportWidth:  1440,
+		ViewportHeight: 900,
+		HeaderOrder:    safariHeaderOrder,
+	},
+	"safari-ios": {
+		Name:           "safari-ios",
+		HelloID:        utls.HelloIOS_14,
+		Engine:         "webkit",
+		UserAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 14_8 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1.2 Mobile/15E148 Safari/604.1",
+		AcceptLanguage: "ru-RU,ru;q=0.9",
+		AcceptEncoding: "gzip, deflate, br",
+		Locale:         "ru-RU",
+		ViewportWidth:  390,
+		ViewportHeight: 844,
+		IsMobile:       true,
+		HeaderOrder:    safariHeaderOrder,
+	},
+}
+
+// LookupProfile возвращает профиль по имени.
+func LookupProfile(name string) (*BrowserProfile, error) {
+	p, ok := profiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный профиль браузера %q (доступны: %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	return p, nil
+}
+
+// ProfileNames возвращает имена всех профилей в алфавитном порядке.
+func ProfileNames() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// defaultProfile — профиль по умолчанию (всегда существует).
+func defaultProfile() *BrowserProfile {
+	return profiles[DefaultProfileName]
+}
+
+// clientHints — заголовки sec-ch-ua* профиля (пусто, если браузер их не шлёт).
+func (p *BrowserProfile) clientHints() map[string]string {
+	if p.SecCHUA == "" {
+		return nil
+	}
+	return map[string]string{
+		"sec-ch-ua":          p.SecCHUA,
+		"sec-ch-ua-mobile":   p.SecCHUAMobile,
+		"sec-ch-ua-platform": p.SecCHUAPlatform,
+	}
+}
@@ -0,0 +1,281 @@
+package lenta
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProxyCooldown    = 5 * time.Minute
+	defaultProxyMaxFailures = 3
+)
+
+// ProxyStrategy — способ выбора следующего прокси из пула.
+
+type ProxyStrategy string
+
+const (
+	ProxyRoundRobin    ProxyStrategy = "round-robin"
+	ProxyRandom        ProxyStrategy = "random"
+	ProxyLeastFailures ProxyStrategy = "least-failures"
+)
+
+// ParseProxyStrategy разбирает стратегию из флага CLI.
+func ParseProxyStrategy(s string) (ProxyStrategy, error) {
+	switch st := ProxyStrategy(strings.ToLower(strings.TrimSpace(s))); st {
+	case ProxyRoundRobin, ProxyRandom, ProxyLeastFailures:
+		return st, nil
+	case "":
+		return ProxyRoundRobin, nil
+	default:
+		return "", fmt.Errorf("неизвестная стратегия прокси: %q", s)
+	}
+}
+
+// ProxyStats — статистика одного прокси.
+
+type ProxyStats struct {
+	URL            string // без пароля
+	Successes      int
+	Failures       int
+	LastFailure    string
+	Healthy        bool
+	UnhealthyUntil time.Time
+}
+
+type proxyState struct {
+	url            *url.URL
+	successes      int
+	failures       int // всего
+	streak         int // подряд
+	lastFailure    string
+	unhealthyUntil time.Time
+}
+
+// ProxyPool — набор прокси с ротацией и учётом здоровья.
+//
+// Прокси помечается нездоровым после MaxFailures ошибок подряд
+// (CONNECT, TLS handshake, 403) и не выдаётся, пока не пройдёт Cooldown.
+// Если нездоровы все, выдаётся тот, чей cooldown закончится раньше всех —
+// обход не останавливается целиком.
+
+type ProxyPool struct {
+	Strategy    ProxyStrategy
+	Cooldown    time.Duration
+	MaxFailures int
+
+	mu      sync.Mutex
+	proxies []*proxyState
+	next    int
+	rnd     *rand.Rand
+	now     func() time.Time // часы cooldown-а; в тестах подменяются
+}
+
+// NewProxyPool создаёт пул из списка URL прокси.
+func NewProxyPool(urls []string, strategy ProxyStrategy) (*ProxyPool, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("пустой список прокси")
+	}
+
+	p := &ProxyPool{
+		Strategy:    strategy,
+		Cooldown:    defaultProxyCooldown,
+		MaxFailures: defaultProxyMaxFailures,
+		rnd:         rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:         time.Now,
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("неверный URL прокси %q: %w", raw, err)
+		}
+		if err := validateProxyURL(u); err != nil {
+			return nil, fmt.Errorf("прокси %q: %w", u.Redacted(), err)
+		}
+		p.proxies = append(p.proxies, &proxyState{url: u})
+	}
+	return p, nil
+}
+
+// LoadProxyList читает прокси из файла: по одному на строку,
+// пустые строки и строки с # пропускаются.
+
+func LoadProxyList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, sc.Err()
+}
+
+// Pick выбирает прокси по стратегии пула.
+func (p *ProxyPool) Pick() *url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var healthy []int
+	for i, ps := range p.proxies {
+		if !now.Before(ps.unhealthyUntil) {
+			healthy = append(healthy, i)
+		}
+	}
+
+	if len(healthy) == 0 {
+		best := p.proxies[0]
+		for _, ps := range p.proxies[1:] {
+			if ps.unhealthyUntil.Before(best.unhealthyUntil) {
+				best = ps
+			}
+		}
+		log.Printf("[WARN] Все прокси в cooldown — используется %s", best.url.Redacted())
+		return best.url
+	}
+
+	switch p.Strategy {
+	case ProxyRandom:
+		return p.proxies[healthy[p.rnd.Intn(len(healthy))]].url
+	case ProxyLeastFailures:
+		best := p.proxies[healthy[0]]
+		for _, i := range healthy[1:] {
+			if p.proxies[i].failures < best.failures {
+				best = p.proxies[i]
+			}
+		}
+		return best.url
+	default:
+		for range p.proxies {
+			i := p.next % len(p.proxies)
+			p.next++
+			if !now.Before(p.proxies[i].unhealthyUntil) {
+				return p.proxies[i].url
+			}
+		}
+		return p.proxies[healthy[0]].url
+	}
+}
+
+// Healthy — прокси не находится в cooldown.
+func (p *ProxyPool) Healthy(u *url.URL) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ps := p.find(u)
+	return ps == nil || !p.now().Before(ps.unhealthyUntil)
+}
+
+// Lookup возвращает прокси пула с тем же адресом, что и raw (nil — такого нет).
+func (p *ProxyPool) Lookup(raw string) *url.URL {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ps := p.find(u); ps != nil {
+		return ps.url
+	}
+	return nil
+}
+
+// MarkSuccess сбрасывает серию ошибок прокси.
+func (p *ProxyPool) MarkSuccess(u *url.URL) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ps := p.find(u); ps != nil {
+		ps.successes++
+		ps.streak = 0
+	}
+}
+
+// MarkFailure учитывает ошибку прокси; после MaxFailures ошибок подряд
+// прокси уходит в cooldown.
+
+func (p *ProxyPool) MarkFailure(u *url.URL, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ps := p.find(u)
+	if ps == nil {
+		return
+	}
+	ps.failures++
+	ps.streak++
+	ps.lastFailure = reason
+
+	if ps.streak >= p.MaxFailures {
+		ps.unhealthyUntil = p.now().Add(p.Cooldown)
+		ps.streak = 0
+		log.Printf("[WARN] Прокси %s помечен нездоровым (%s), cooldown %s", u.Redacted(), reason, p.Cooldown)
+	}
+}
+
+// Stats возвращает статистику по всем прокси пула.
+func (p *ProxyPool) Stats() []ProxyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	out := make([]ProxyStats, 0, len(p.proxies))
+	for _, ps := range p.proxies {
+		out = append(out, ProxyStats{
+			URL:            ps.url.Redacted(),
+			Successes:      ps.successes,
+			Failures:       ps.failures,
+			LastFailure:    ps.lastFailure,
+			Healthy:        !now.Before(ps.unhealthyUntil),
+			UnhealthyUntil: ps.unhealthyUntil,
+		})
+	}
+	return out
+}
+
+func (p *ProxyPool) find(u *url.URL) *proxyState {
+	for _, ps := range p.proxies {
+		if ps.url == u || ps.url.String() == u.String() {
+			return ps
+		}
+	}
+	return nil
+}
+
+// proxyCtxKey — ключ контекста с прокси, через который должен идти прогрев.
+type proxyCtxKey struct{}
+
+// WithProxy возвращает контекст, в котором SessionWarmer должен
+// использовать указанный прокси — чтобы exit IP браузера и API-клиента совпадали.
+
+func WithProxy(ctx context.Context, u *url.URL) context.Context {
+	if u == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, proxyCtxKey{}, u)
+}
+
+// ProxyFromContext возвращает прокси, заданный через WithProxy.
+func ProxyFromContext(ctx context.Context) *url.URL {
+	u, _ := ctx.Value(proxyCtxKey{}).(*url.URL)
+	return u
+}
@@ -0,0 +1,169 @@
+package lenta
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var testProxies = []string{"http://a:8080", "http://b:8080", "http://c:8080"}
+
+// newTestProxyPool возвращает пул с ручными часами: cooldown идёт только
+// при сдвиге *clock.
+func newTestProxyPool(t *testing.T, strategy ProxyStrategy) (*ProxyPool, *time.Time) {
+	t.Helper()
+	p, err := NewProxyPool(testProxies, strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return clock }
+	p.rnd = rand.New(rand.NewSource(1))
+	p.Cooldown = time.Minute
+	return p, &clock
+}
+
+func picks(p *ProxyPool, n int) []string {
+	var out []string
+	for range n {
+		out = append(out, p.Pick().Host)
+	}
+	return out
+}
+
+func failN(p *ProxyPool, raw string, n int) {
+	u := p.Lookup(raw)
+	for range n {
+		p.MarkFailure(u, "403")
+	}
+}
+
+func TestProxyPoolStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy ProxyStrategy
+		prepare  func(p *ProxyPool)
+		want     []string // nil — любые, кроме excluded
+		excluded string
+	}{
+		{name: "round-robin", strategy: ProxyRoundRobin,
+			want: []string{"a:8080", "b:8080", "c:8080", "a:8080"}},
+		{name: "round-robin пропускает cooldown", strategy: ProxyRoundRobin,
+			prepare: func(p *ProxyPool) { failN(p, testProxies[1], 3) },
+			want:    []string{"a:8080", "c:8080", "a:8080", "c:8080"}},
+		{name: "least-failures", strategy: ProxyLeastFailures,
+			prepare: func(p *ProxyPool) { failN(p, testProxies[0], 2); failN(p, testProxies[2], 1) },
+			want:    []string{"b:8080", "b:8080"}},
+		{name: "least-failures считает всего, а не подряд", strategy: ProxyLeastFailures,
+			prepare: func(p *ProxyPool) {
+				failN(p, testProxies[0], 1)
+				p.MarkSuccess(p.Lookup(testProxies[0]))
+				failN(p, testProxies[1], 2)
+				failN(p, testProxies[2], 2)
+			},
+			want: []string{"a:8080"}},
+		{name: "random только здоровые", strategy: ProxyRandom,
+			prepare:  func(p *ProxyPool) { failN(p, testProxies[0], 3) },
+			excluded: "a:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestProxyPool(t, tt.strategy)
+			if tt.prepare != nil {
+				tt.prepare(p)
+			}
+			if tt.want == nil {
+				seen := make(map[string]bool)
+				for _, host := range picks(p, 50) {
+					seen[host] = true
+				}
+				if seen[tt.excluded] || len(seen) != len(testProxies)-1 {
+					t.Errorf("выбраны %v, ожидались все, кроме %s", seen, tt.excluded)
+				}
+				return
+			}
+			got := picks(p, len(tt.want))
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("выбраны %v, ожидались %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyPoolCooldown(t *testing.T) {
+	p, clock := newTestProxyPool(t, ProxyRoundRobin)
+	a := p.Lookup(testProxies[0])
+
+	// Успех сбрасывает серию: 2 + 2 ошибки не подряд — прокси здоров.
+	failN(p, testProxies[0], 2)
+	p.MarkSuccess(a)
+	failN(p, testProxies[0], 2)
+	if !p.Healthy(a) {
+		t.Fatal("прокси в cooldown без трёх ошибок подряд")
+	}
+	p.MarkFailure(a, "TLS")
+	if p.Healthy(a) {
+		t.Fatal("после трёх ошибок подряд прокси здоров")
+	}
+	if st := p.Stats()[0]; st.Failures != 5 || st.Successes != 1 || st.LastFailure != "TLS" || st.Healthy {
+		t.Errorf("Stats() = %+v", st)
+	}
+
+	*clock = clock.Add(30 * time.Second)
+	if p.Healthy(a) {
+		t.Fatal("cooldown закончился раньше времени")
+	}
+	*clock = clock.Add(30 * time.Second)
+	if !p.Healthy(a) {
+		t.Fatal("прокси не вернулся после cooldown")
+	}
+
+	// Все в cooldown — выдаётся тот, кто освободится раньше.
+	failN(p, testProxies[1], 3)
+	*clock = clock.Add(time.Second)
+	failN(p, testProxies[2], 3)
+	*clock = clock.Add(time.Second)
+	failN(p, testProxies[0], 3)
+	if got := p.Pick().Host; got != "b:8080" {
+		t.Errorf("все в cooldown: выбран %s, ожидался b:8080", got)
+	}
+}
+
+func TestClientPinProxy(t *testing.T) {
+	pool, _ := newTestProxyPool(t, ProxyRoundRobin)
+	c, err := NewClient(&Config{Domain: "lenta.com", SessionToken: "test-token", ProxyPool: pool, PinProxy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	first := c.proxyForRequest()
+	for range 3 {
+		if u := c.proxyForRequest(); u != first {
+			t.Fatalf("закреплённый прокси сменился: %s → %s", first.Host, u.Host)
+		}
+	}
+	if c.SessionProxy() != first {
+		t.Error("прогрев идёт не через закреплённый прокси")
+	}
+
+	failN(pool, first.String(), 3)
+	if u := c.proxyForRequest(); u == first || !pool.Healthy(u) {
+		t.Errorf("после cooldown закреплён %s", u.Host)
+	}
+
+	// Прокси сохранённой сессии закрепляется, если он есть в пуле.
+	c.pinProxy("http://c:8080")
+	if u := c.proxyForRequest(); u.Host != "c:8080" {
+		t.Errorf("закреплён %s, ожидался прокси сессии c:8080", u.Host)
+	}
+	c.pinProxy("http://unknown:8080")
+	if u := c.proxyForRequest(); u.Host != "c:8080" {
+		t.Errorf("чужой прокси сессии сменил закреплённый на %s", u.Host)
+	}
+	if c.currentSessionProxy() != "http://c:8080" {
+		t.Errorf("currentSessionProxy() = %q", c.currentSessionProxy())
+	}
+}
@@ -0,0 +1,220 @@
+package lenta
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SortMode — сортировка выдачи catalog API (поле sort запроса).
+
+type SortMode struct {
+	Type  string `json:"type"`
+	Order string `json:"order"`
+}
+
+var (
+	SortPopular   = SortMode{Type: "popular", Order: "desc"}
+	SortPriceAsc  = SortMode{Type: "price", Order: "asc"}
+	SortPriceDesc = SortMode{Type: "price", Order: "desc"}
+	SortDiscount  = SortMode{Type: "discount", Order: "desc"}
+	SortRating    = SortMode{Type: "rating", Order: "desc"}
+)
+
+// sortModes — имена сортировок для CLI.
+var sortModes = map[string]SortMode{
+	"popular":    SortPopular,
+	"price-asc":  SortPriceAsc,
+	"price-desc": SortPriceDesc,
+	"discount":   SortDiscount,
+	"rating":     SortRating,
+}
+
+// ParseSort разбирает сортировку из флага CLI ("" — popular).
+func ParseSort(s string) (SortMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return SortPopular, nil
+	}
+	if m, ok := sortModes[s]; ok {
+		return m, nil
+	}
+	return SortMode{}, fmt.Errorf("неизвестная сортировка %q (доступны: %s)", s, strings.Join(SortNames(), ", "))
+}
+
+// SortNames возвращает имена сортировок в алфавитном порядке.
+func SortNames() []string {
+	names := make([]string, 0, len(sortModes))
+	for name := range sortModes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Ключи фильтров каталога.
+const (
+	FilterPrice    = "price"
+	FilterWeight   = "weight"
+	FilterBrand    = "brand"
+	FilterDiscount = "discount"
+)
+
+// RangeValue — выбранный диапазон range-фильтра. nil — граница не задана.
+// Цена задаётся в копейках, как в Prices.
+
+type RangeValue struct {
+	Key string   `json:"key"`
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// CheckboxValue — выбранные значения checkbox- или multicheckbox-фильтра.
+// Без значений — фильтр-переключатель (например, «только со скидкой»).
+
+type CheckboxValue struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values,omitempty"`
+}
+
+// CatalogQuery — запрос товаров категории: фильтры и сортировка.
+// Нулевое значение — без фильтров, сортировка popular.
+//
+//	q := NewCatalogQuery().SortBy(SortPriceAsc).Checkbox(FilterBrand, "Простоквашино")
+
+type CatalogQuery struct {
+	Sort            SortMode
+	Ranges          []RangeValue
+	Checkboxes      []CheckboxValue
+	Multicheckboxes []CheckboxValue
+}
+
+// NewCatalogQuery возвращает запрос без фильтров с сортировкой popular.
+func NewCatalogQuery() *CatalogQuery {
+	return &CatalogQuery{Sort: SortPopular}
+}
+
+// SortBy задаёт сортировку.
+func (q *CatalogQuery) SortBy(m SortMode) *CatalogQuery {
+	q.Sort = m
+	return q
+}
+
+// Between добавляет range-фильтр; nil-граница не ограничивает.
+func (q *CatalogQuery) Between(key string, min, max *float64) *CatalogQuery {
+	q.Ranges = append(q.Ranges, RangeValue{Key: key, Min: min, Max: max})
+	return q
+}
+
+// Checkbox добавляет checkbox-фильтр (бренд, «только со скидкой»).
+func (q *CatalogQuery) Checkbox(key string, values ...string) *CatalogQuery {
+	q.Checkboxes = appendCheckbox(q.Checkboxes, key, values)
+	return q
+}
+
+// Multicheckbox добавляет значения multicheckbox-фильтра.
+func (q *CatalogQuery) Multicheckbox(key string, values ...string) *CatalogQuery {
+	q.Multicheckboxes = appendCheckbox(q.Multicheckboxes, key, values)
+	return q
+}
+
+// appendCheckbox объединяет значения одного ключа в одну запись.
+func appendCheckbox(list []CheckboxValue, key string, values []string) []CheckboxValue {
+	for i := range list {
+		if list[i].Key == key {
+			list[i].Values = append(list[i].Values, values...)
+			return list
+		}
+	}
+	return append(list, CheckboxValue{Key: key, Values: values})
+}
+
+// payload строит тело POST catalog/items.
+func (q *CatalogQuery) payload(categoryID, offset, limit int) map[string]interface{} {
+	if q == nil {
+		q = NewCatalogQuery()
+	}
+	sortMode := q.Sort
+	if sortMode.Type == "" {
+		sortMode = SortPopular
+	}
+
+	// API ожидает массивы, а не null.
+	ranges, checkbox, multi := q.Ranges, q.Checkboxes, q.Multicheckboxes
+	if ranges == nil {
+		ranges = []RangeValue{}
+	}
+	if checkbox == nil {
+		checkbox = []CheckboxValue{}
+	}
+	if multi == nil {
+		multi = []CheckboxValue{}
+	}
+
+	return map[string]interface{}{
+		"categoryId": categoryID,
+		"filters": map[string]interface{}{
+			"range":         ranges,
+			"checkbox":      checkbox,
+			"multicheckbox": multi,
+		},
+		"limit":  limit,
+		"offset": offset,
+		"sort":   sortMode,
+	}
+}
+
+// ParseRange разбирает диапазон вида "50..200", "50.." или "..200".
+func ParseRange(s string) (min, max *float64, err error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "..")
+	if !ok {
+		return nil, nil, fmt.Errorf("диапазон %q: ожидается формат от..до", s)
+	}
+	parse := func(v string) (*float64, error) {
+		v = strings.TrimSpace(strings.ReplaceAll(v, ",", "."))
+		if v == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("диапазон %q: %w", s, err)
+		}
+		return &f, nil
+	}
+	if min, err = parse(from); err != nil {
+		return nil, nil, err
+	}
+	if max, err = parse(to); err != nil {
+		return nil, nil, err
+	}
+	if min == nil && max == nil {
+		return nil, nil, fmt.Errorf("диапазон %q: не задана ни одна граница", s)
+	}
+	if min != nil && max != nil && *min > *max {
+		return nil, nil, fmt.Errorf("диапазон %q: начало больше конца", s)
+	}
+	return min, max, nil
+}
+
+// ParseFilter разбирает фильтр вида "brand=Простоквашино" или "brand=A,B".
+// Без "=" — фильтр-переключатель, например "discount".
+func ParseFilter(s string) (key string, values []string, err error) {
+	key, raw, hasValues := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", nil, fmt.Errorf("фильтр %q: не задан ключ", s)
+	}
+	if !hasValues {
+		return key, nil, nil
+	}
+	for _, v := range strings.Split(raw, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return "", nil, fmt.Errorf("фильтр %q: пустое значение", s)
+	}
+	return key, values, nil
+}
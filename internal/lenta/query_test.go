@@ -0,0 +1,94 @@
+package lenta
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		in       string
+		min, max *float64
+		wantErr  bool
+	}{
+		{in: "50..200", min: f(50), max: f(200)},
+		{in: "49,9..", min: f(49.9)},
+		{in: " ..200 ", max: f(200)},
+		{in: "5..5", min: f(5), max: f(5)},
+		{in: "..", wantErr: true},
+		{in: "200..50", wantErr: true},
+		{in: "50-200", wantErr: true},
+		{in: "a..1", wantErr: true},
+	}
+	for _, tt := range tests {
+		min, max, err := ParseRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRange(%q): err = %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(min, tt.min) || !reflect.DeepEqual(max, tt.max) {
+			t.Errorf("ParseRange(%q) = %v..%v", tt.in, min, max)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		in      string
+		key     string
+		values  []string
+		wantErr bool
+	}{
+		{in: "brand=Простоквашино", key: "brand", values: []string{"Простоквашино"}},
+		{in: "brand= A , ,B", key: "brand", values: []string{"A", "B"}},
+		{in: "discount", key: "discount"},
+		{in: "brand=", wantErr: true},
+		{in: "=A", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, values, err := ParseFilter(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFilter(%q): err = %v", tt.in, err)
+			continue
+		}
+		if key != tt.key || !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("ParseFilter(%q) = %q, %q", tt.in, key, values)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	if m, err := ParseSort(""); err != nil || m != SortPopular {
+		t.Errorf("ParseSort(\"\") = %v, %v", m, err)
+	}
+	if m, err := ParseSort("Price-Asc"); err != nil || m != SortPriceAsc {
+		t.Errorf("ParseSort(Price-Asc) = %v, %v", m, err)
+	}
+	if _, err := ParseSort("cheap"); err == nil {
+		t.Error("ParseSort(cheap): ожидалась ошибка")
+	}
+}
+
+// TestCatalogQueryPayload — пустые фильтры уходят массивами, значения
+// одного ключа объединяются.
+func TestCatalogQueryPayload(t *testing.T) {
+	var nilQuery *CatalogQuery
+	data, _ := json.Marshal(nilQuery.payload(128, 40, 20))
+	want := `{"categoryId":128,"filters":{"checkbox":[],"multicheckbox":[],"range":[]},"limit":20,"offset":40,"sort":{"type":"popular","order":"desc"}}`
+	if string(data) != want {
+		t.Errorf("пустой запрос:\n%s\nожидался\n%s", data, want)
+	}
+
+	max := 20000.0
+	q := NewCatalogQuery().SortBy(SortPriceDesc).
+		Between(FilterPrice, nil, &max).
+		Checkbox(FilterBrand, "A").Checkbox(FilterBrand, "B").
+		Checkbox(FilterDiscount)
+	data, _ = json.Marshal(q.payload(1, 0, 10))
+	want = `{"categoryId":1,"filters":{"checkbox":[{"key":"brand","values":["A","B"]},{"key":"discount"}],"multicheckbox":[],"range":[{"key":"price","max":20000}]},"limit":10,"offset":0,"sort":{"type":"price","order":"desc"}}`
+	if string(data) != want {
+		t.Errorf("запрос с фильтрами:\n%s\nожидался\n%s", data, want)
+	}
+}
@@ -0,0 +1,239 @@
+package lenta
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxSlowdown    = 16.0 // максимальное замедление адаптивного лимитера
+	slowdownFactor = 2.0  // во сколько раз замедляться на 429 / anti-bot
+	recoverFactor  = 0.9  // насколько ускоряться после успешного запроса
+)
+
+// DelayDist — распределение случайной паузы перед запросом.
+
+type DelayDist string
+
+const (
+	DelayNone      DelayDist = "none"
+	DelayUniform   DelayDist = "uniform"   // равномерно в [Min, Max]
+	DelayNormal    DelayDist = "normal"    // нормально вокруг середины, σ = (Max-Min)/6
+	DelayLogNormal DelayDist = "lognormal" // чаще короткие паузы, изредка длинные — как у человека
+)
+
+// ParseDelayDist разбирает распределение из флага CLI.
+func ParseDelayDist(s string) (DelayDist, error) {
+	switch d := DelayDist(strings.ToLower(strings.TrimSpace(s))); d {
+	case DelayNone, DelayUniform, DelayNormal, DelayLogNormal:
+		return d, nil
+	case "":
+		return DelayNone, nil
+	default:
+		return "", fmt.Errorf("неизвестное распределение паузы: %q", s)
+	}
+}
+
+// Delay — случайная «человеческая» пауза. Значение всегда в [Min, Max].
+
+type Delay struct {
+	Dist DelayDist
+	Min  time.Duration
+	Max  time.Duration
+}
+
+func (d Delay) sample(rnd *rand.Rand) time.Duration {
+	if d.Max < d.Min {
+		d.Max = d.Min
+	}
+	span := float64(d.Max - d.Min)
+
+	var v float64
+	switch d.Dist {
+	case DelayUniform:
+		v = float64(d.Min) + rnd.Float64()*span
+	case DelayNormal:
+		v = float64(d.Min) + span/2 + rnd.NormFloat64()*span/6
+	case DelayLogNormal:
+		// Медиана — на четверти диапазона, хвост вправо.
+		median := float64(d.Min) + span/4
+		if median <= 0 {
+			median = float64(time.Millisecond)
+		}
+		v = math.Exp(math.Log(median) + 0.6*rnd.NormFloat64())
+	default:
+		return 0
+	}
+	return time.Duration(math.Max(float64(d.Min), math.Min(float64(d.Max), v)))
+}
+
+// RateLimit — token bucket: RPS токенов в секунду, запас Burst,
+// плюс случайная пауза Delay после получения токена.
+
+type RateLimit struct {
+	RPS   float64 // 0 — без ограничения частоты
+	Burst int     // 0 — 1
+	Delay Delay
+}
+
+// RateLimitConfig — лимиты клиента. Запрос ждёт во всех подходящих бакетах:
+// общем, бакете своего эндпоинта и бакете своего прокси.
+
+type RateLimitConfig struct {
+	Global      RateLimit
+	PerEndpoint map[string]RateLimit // ключ — путь запроса, например /api-gateway/v1/catalog/items
+	PerProxy    map[string]RateLimit // ключ — host:port прокси; "*" — для каждого прокси без своей записи
+
+	// Adaptive замедляет бакеты запроса вдвое (до 16×) на 429 и anti-bot
+	// и постепенно возвращает скорость после успешных ответов.
+	Adaptive bool
+}
+
+// bucket — token bucket с адаптивным замедлением.
+type bucket struct {
+	name   string
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+	slow   float64
+}
+
+func newBucket(name string, limit RateLimit) *bucket {
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+	return &bucket{name: name, limit: limit, tokens: float64(limit.Burst), slow: 1}
+}
+
+// reserve забирает токен и возвращает, сколько ждать до его появления.
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if b.limit.RPS <= 0 {
+		return 0
+	}
+	rate := b.limit.RPS / b.slow
+	if !b.last.IsZero() {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+// unreserve возвращает токен, взятый reserve, если запрос так и не ушёл.
+func (b *bucket) unreserve() {
+	if b.limit.RPS > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+1)
+	}
+}
+
+// rateLimiter — набор бакетов клиента.
+type rateLimiter struct {
+	mu      sync.Mutex
+	cfg     RateLimitConfig
+	buckets map[string]*bucket
+	rnd     *rand.Rand
+}
+
+func newRateLimiter(cfg *RateLimitConfig) *rateLimiter {
+	if cfg == nil {
+		return nil
+	}
+	return &rateLimiter{
+		cfg:     *cfg,
+		buckets: make(map[string]*bucket),
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// bucketsLocked возвращает бакеты, в которых должен отстоять запрос.
+func (l *rateLimiter) bucketsLocked(endpoint, proxy string) []*bucket {
+	var out []*bucket
+	get := func(key string, limit RateLimit) {
+		b, ok := l.buckets[key]
+		if !ok {
+			b = newBucket(key, limit)
+			l.buckets[key] = b
+		}
+		out = append(out, b)
+	}
+
+	get("global", l.cfg.Global)
+	if lim, ok := l.cfg.PerEndpoint[endpoint]; ok {
+		get("endpoint:"+endpoint, lim)
+	}
+	if proxy != "" {
+		if lim, ok := l.cfg.PerProxy[proxy]; ok {
+			get("proxy:"+proxy, lim)
+		} else if lim, ok := l.cfg.PerProxy["*"]; ok {
+			get("proxy:"+proxy, lim)
+		}
+	}
+	return out
+}
+
+// wait блокирует до разрешения на запрос: токен во всех бакетах плюс случайная пауза.
+// При отмене ожидания токены возвращаются: запрос не ушёл и не должен
+// замедлять следующие. nil-лимитер ничего не ограничивает.
+
+func (l *rateLimiter) wait(ctx context.Context, endpoint, proxy string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	buckets := l.bucketsLocked(endpoint, proxy)
+	var wait, delay time.Duration
+	for _, b := range buckets {
+		wait = max(wait, b.reserve(now))
+		d := time.Duration(float64(b.limit.Delay.sample(l.rnd)) * b.slow)
+		delay = max(delay, d)
+	}
+	l.mu.Unlock()
+
+	if err := sleepContext(ctx, wait+delay); err != nil {
+		l.mu.Lock()
+		for _, b := range buckets {
+			b.unreserve()
+		}
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// observe сообщает лимитеру результат запроса для адаптивного замедления:
+// 429 и anti-bot замедляют, успех (KindUnknown) постепенно ускоряет, прочие ошибки не влияют.
+func (l *rateLimiter) observe(endpoint, proxy string, kind ErrorKind) {
+	if l == nil || !l.cfg.Adaptive {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	throttled := kind == KindRateLimited || kind == KindAntiBot
+	if !throttled && kind != KindUnknown {
+		return
+	}
+	for _, b := range l.bucketsLocked(endpoint, proxy) {
+		if throttled {
+			prev := b.slow
+			b.slow = math.Min(maxSlowdown, b.slow*slowdownFactor)
+			if b.slow != prev {
+				log.Printf("[RATE] %s: %s — замедление %.0f×", b.name, kind, b.slow)
+			}
+			continue
+		}
+		b.slow = math.Max(1, b.slow*recoverFactor)
+	}
+}
@@ -0,0 +1,132 @@
+package lenta
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBucketBurst(t *testing.T) {
+	b := newBucket("global", RateLimit{RPS: 2, Burst: 3})
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Запас Burst уходит сразу, дальше — по токену раз в 1/RPS.
+	for i := range 3 {
+		if d := b.reserve(t0); d != 0 {
+			t.Fatalf("запрос %d из запаса ждёт %s", i+1, d)
+		}
+	}
+	if d := b.reserve(t0); d != 500*time.Millisecond {
+		t.Errorf("4-й запрос ждёт %s, ожидалось 500ms", d)
+	}
+	if d := b.reserve(t0); d != time.Second {
+		t.Errorf("5-й запрос ждёт %s, ожидалось 1s", d)
+	}
+
+	// Простой пополняет запас, но не выше Burst.
+	t1 := t0.Add(time.Hour)
+	for i := range 3 {
+		if d := b.reserve(t1); d != 0 {
+			t.Fatalf("после простоя запрос %d ждёт %s", i+1, d)
+		}
+	}
+	if d := b.reserve(t1); d == 0 {
+		t.Error("запас после простоя больше Burst")
+	}
+
+	if d := newBucket("none", RateLimit{}).reserve(t0); d != 0 {
+		t.Errorf("бакет без RPS ждёт %s", d)
+	}
+}
+
+func TestRateLimiterAdaptive(t *testing.T) {
+	l := newRateLimiter(&RateLimitConfig{
+		Global:   RateLimit{RPS: 1},
+		PerProxy: map[string]RateLimit{"*": {RPS: 1}},
+		Adaptive: true,
+	})
+	const endpoint, proxy = "/api-gateway/v1/catalog/items", "a:8080"
+	slow := func(key string) float64 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.bucketsLocked(endpoint, proxy)
+		return l.buckets[key].slow
+	}
+
+	l.observe(endpoint, proxy, KindRateLimited)
+	if s := slow("global"); s != 2 {
+		t.Fatalf("после 429 замедление %v×, ожидалось 2×", s)
+	}
+	if s := slow("proxy:" + proxy); s != 2 {
+		t.Errorf("бакет прокси не замедлен: %v×", s)
+	}
+	for range 10 {
+		l.observe(endpoint, proxy, KindAntiBot)
+	}
+	if s := slow("global"); s != maxSlowdown {
+		t.Fatalf("замедление %v×, ожидался потолок %v×", s, maxSlowdown)
+	}
+
+	// Прочие ошибки скорость не меняют.
+	l.observe(endpoint, proxy, KindServer)
+	if s := slow("global"); s != maxSlowdown {
+		t.Errorf("5xx изменила замедление: %v×", s)
+	}
+
+	// Замедленный бакет выдаёт токены реже.
+	b := l.buckets["global"]
+	now := time.Now()
+	b.tokens, b.last = 0, now
+	if d := b.reserve(now); d != 16*time.Second {
+		t.Errorf("при 16× ожидание %s, ожидалось 16s", d)
+	}
+
+	// Успехи постепенно возвращают скорость, но не быстрее исходной.
+	l.observe(endpoint, proxy, KindUnknown)
+	if s := slow("global"); s >= maxSlowdown || s <= 1 {
+		t.Errorf("после одного успеха замедление %v×", s)
+	}
+	for range 100 {
+		l.observe(endpoint, proxy, KindUnknown)
+	}
+	if s := slow("global"); s != 1 {
+		t.Errorf("после серии успехов замедление %v×, ожидалось 1×", s)
+	}
+
+	// Без Adaptive ответы не влияют.
+	fixed := newRateLimiter(&RateLimitConfig{Global: RateLimit{RPS: 1}})
+	fixed.observe(endpoint, "", KindRateLimited)
+	if len(fixed.buckets) != 0 {
+		t.Error("неадаптивный лимитер реагирует на 429")
+	}
+}
+
+// TestRateLimiterWaitCancel — отменённое ожидание возвращает токен:
+// следующий запрос ждёт один интервал, а не два.
+func TestRateLimiterWaitCancel(t *testing.T) {
+	const interval = 200 * time.Millisecond
+	l := newRateLimiter(&RateLimitConfig{Global: RateLimit{RPS: float64(time.Second / interval)}})
+	l.rnd = rand.New(rand.NewSource(1))
+
+	if err := l.wait(context.Background(), "/", ""); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, "/", ""); err == nil {
+		t.Fatal("ожидание не прервано отменой")
+	}
+
+	l.mu.Lock()
+	d := l.buckets["global"].reserve(time.Now())
+	l.mu.Unlock()
+	if d > interval {
+		t.Errorf("после отмены следующий запрос ждёт %s, ожидалось не больше %s", d, interval)
+	}
+
+	var nilLimiter *rateLimiter
+	if err := nilLimiter.wait(context.Background(), "/", ""); err != nil {
+		t.Errorf("nil-лимитер: %v", err)
+	}
+}
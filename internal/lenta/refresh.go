@@ -0,0 +1,117 @@
+package lenta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+)
+
+const defaultMaxSessionRefreshes = 3
+
+// errRefreshLimit — исчерпан лимит перепрогревов за запуск.
+var errRefreshLimit = errors.New("исчерпан лимит обновлений сессии")
+
+// sessionRefresher перепрогревает сессию, когда API начинает отвечать 401/403.
+//
+// Запросы читают generation под RLock перед отправкой, а обновление держит
+// Lock на всё время прогрева — новые запросы встают на паузу до его окончания.
+// Если несколько запросов получили отказ одновременно, браузер запускается
+// один раз: остальные видят, что generation уже сменилась, и просто повторяют запрос.
+
+type sessionRefresher struct {
+	mu         sync.RWMutex
+	warmer     SessionWarmer
+	max        int
+	count      int
+	generation uint64
+	onRefresh  func(*Session)
+}
+
+// SetSessionWarmer включает автоматическое обновление сессии.
+// maxRefreshes ограничивает число перепрогревов за время жизни клиента (0 — по умолчанию 3).
+// onRefresh (может быть nil) вызывается с новой сессией — например, чтобы сохранить её на диск.
+
+func (c *Client) SetSessionWarmer(w SessionWarmer, maxRefreshes int, onRefresh func(*Session)) {
+	if maxRefreshes <= 0 {
+		maxRefreshes = defaultMaxSessionRefreshes
+	}
+
+	c.refresher.mu.Lock()
+	defer c.refresher.mu.Unlock()
+	c.refresher.warmer = w
+	c.refresher.max = maxRefreshes
+	c.refresher.onRefresh = onRefresh
+}
+
+// SessionRefreshes возвращает, сколько раз сессия была перепрогрета.
+func (c *Client) SessionRefreshes() int {
+	c.refresher.mu.RLock()
+	defer c.refresher.mu.RUnlock()
+	return c.refresher.count
+}
+
+// prepareRequest ждёт окончания идущего обновления, выставляет заголовки
+// текущей сессии и возвращает её номер.
+func (c *Client) prepareRequest(req *http.Request) (uint64, bool) {
+	c.refresher.mu.RLock()
+	defer c.refresher.mu.RUnlock()
+	c.setHeaders(req)
+	return c.refresher.generation, c.refresher.warmer != nil
+}
+
+// refreshSession перепрогревает сессию, если её ещё не обновил другой запрос.
+
+func (c *Client) refreshSession(ctx context.Context, seen uint64) error {
+	r := &c.refresher
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.generation != seen {
+		return nil
+	}
+	if r.count >= r.max {
+		return errRefreshLimit
+	}
+	r.count++
+
+	log.Printf("[WARN] API отклонил сессию — перепрогрев %d/%d", r.count, r.max)
+
+	sess, err := r.warmer.Warm(WithProxy(ctx, c.SessionProxy()))
+	if err != nil {
+		return fmt.Errorf("ошибка перепрогрева сессии: %w", err)
+	}
+	c.ApplySession(sess)
+	r.generation++
+
+	if r.onRefresh != nil {
+		r.onRefresh(c.Session())
+	}
+	return nil
+}
+
+// isSessionRejected — ответ означает, что сессия или anti-bot cookies больше не принимаются.
+func isSessionRejected(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden
+}
+
+// rewindRequest возвращает копию запроса с перечитанным телом.
+// false — тело нельзя прочитать повторно и запрос не может быть повторён.
+
+func rewindRequest(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req.Clone(req.Context()), true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	out := req.Clone(req.Context())
+	out.Body = body
+	return out, true
+}
@@ -0,0 +1,174 @@
+package lenta
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy — политика повторов запросов в Client.Do.
+//
+// Повторяются сетевые ошибки, ошибки прокси и TLS, 5xx и 429.
+// 400-е (кроме 429), ошибки разбора и отказы anti-bot не повторяются:
+// повтор того же запроса не поможет, а 401/403 обрабатывает перепрогрев сессии.
+
+type RetryPolicy struct {
+	MaxAttempts   int           // всего попыток, включая первую; 1 — без повторов
+	BaseDelay     time.Duration // база экспоненты
+	MaxDelay      time.Duration // потолок паузы без Retry-After
+	MaxRetryAfter time.Duration // потолок паузы из Retry-After
+}
+
+// DefaultRetryPolicy — 4 попытки, пауза от 1s до 30s, Retry-After до 2m.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   4,
+		BaseDelay:     time.Second,
+		MaxDelay:      30 * time.Second,
+		MaxRetryAfter: 2 * time.Minute,
+	}
+}
+
+// Retryable — стоит ли повторять запрос после ошибки вида kind.
+func (p RetryPolicy) Retryable(kind ErrorKind) bool {
+	switch kind {
+	case KindNetwork, KindProxy, KindTLS, KindServer, KindRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// Delay — пауза перед повтором номер attempt (с 1).
+// Exponential backoff с full jitter: случайное значение из [0, min(MaxDelay, BaseDelay·2^(attempt-1))].
+// Если сервер прислал Retry-After, используется он (не больше MaxRetryAfter).
+
+func (p RetryPolicy) Delay(attempt int, retryAfter time.Duration, rnd *rand.Rand) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, p.MaxRetryAfter)
+	}
+
+	ceiling := p.MaxDelay
+	if shift := attempt - 1; shift < 30 {
+		if d := p.BaseDelay << shift; d > 0 && d < ceiling {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rnd.Int63n(int64(ceiling) + 1))
+}
+
+// RetryStats — счётчики повторов клиента.
+
+type RetryStats struct {
+	Retries   int64               // всего повторов
+	Exhausted int64               // запросов, для которых попытки закончились
+	ByKind    map[ErrorKind]int64 // повторы по виду ошибки
+}
+
+type retryState struct {
+	mu     sync.Mutex
+	policy RetryPolicy
+	rnd    *rand.Rand
+	stats  RetryStats
+}
+
+func newRetryState(policy *RetryPolicy) *retryState {
+	p := DefaultRetryPolicy()
+	if policy != nil {
+		p = *policy
+	}
+	if p.MaxAttempts < 1 {
+		p.MaxAttempts = 1
+	}
+	return &retryState{
+		policy: p,
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		stats:  RetryStats{ByKind: make(map[ErrorKind]int64)},
+	}
+}
+
+// next решает, повторять ли попытку attempt, и возвращает паузу.
+func (r *retryState) next(attempt int, kind ErrorKind, retryAfter time.Duration) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.policy.Retryable(kind) {
+		return 0, false
+	}
+	if attempt >= r.policy.MaxAttempts {
+		r.stats.Exhausted++
+		return 0, false
+	}
+	r.stats.Retries++
+	r.stats.ByKind[kind]++
+	return r.policy.Delay(attempt, retryAfter, r.rnd), true
+}
+
+func (r *retryState) snapshot() RetryStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.stats
+	st.ByKind = make(map[ErrorKind]int64, len(r.stats.ByKind))
+	for k, v := range r.stats.ByKind {
+		st.ByKind[k] = v
+	}
+	return st
+}
+
+// RetryStats возвращает счётчики повторов клиента.
+func (c *Client) RetryStats() RetryStats {
+	return c.retry.snapshot()
+}
+
+// retryKind определяет вид неудачи попытки: по ошибке или по статусу ответа.
+// Для успешных и неповторяемых ответов возвращается KindUnknown.
+
+func retryKind(resp *http.Response, err error) (ErrorKind, time.Duration) {
+	if err != nil {
+		if errors.Is(err, ErrCanceled) {
+			return KindUnknown, 0
+		}
+		return KindOf(err), 0
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return KindRateLimited, retryAfter(resp)
+	case resp.StatusCode >= 500:
+		return KindServer, retryAfter(resp)
+	}
+	return KindUnknown, 0
+}
+
+// retryAfter — пауза из Retry-After ответа. Учитывается только для 429 и 503:
+// у прочих статусов заголовок не означает «повторите через N секунд».
+func retryAfter(resp *http.Response) time.Duration {
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return parseRetryAfter(resp.Header)
+	}
+	return 0
+}
+
+// parseRetryAfter разбирает Retry-After: число секунд или HTTP-дата.
+func parseRetryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
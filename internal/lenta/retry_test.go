@@ -0,0 +1,106 @@
+package lenta
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second, MaxRetryAfter: time.Minute}
+	rnd := rand.New(rand.NewSource(1))
+
+	// Full jitter: пауза в [0, min(MaxDelay, BaseDelay·2^(attempt-1))].
+	for attempt, ceiling := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 100: 5 * time.Second} {
+		var maxSeen time.Duration
+		for range 1000 {
+			d := p.Delay(attempt, 0, rnd)
+			if d < 0 || d > ceiling {
+				t.Fatalf("попытка %d: пауза %s вне [0, %s]", attempt, d, ceiling)
+			}
+			maxSeen = max(maxSeen, d)
+		}
+		if maxSeen < ceiling/2 {
+			t.Errorf("попытка %d: максимум паузы %s — нет разброса до %s", attempt, maxSeen, ceiling)
+		}
+	}
+
+	if d := p.Delay(1, 30*time.Second, rnd); d != 30*time.Second {
+		t.Errorf("Retry-After 30s: пауза %s", d)
+	}
+	if d := p.Delay(1, time.Hour, rnd); d != time.Minute {
+		t.Errorf("Retry-After 1h: пауза %s, ожидался потолок MaxRetryAfter", d)
+	}
+	if d := (RetryPolicy{}).Delay(1, 0, rnd); d != 0 {
+		t.Errorf("нулевая политика: пауза %s", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{"", 0, 0},
+		{"120", 2 * time.Minute, 2 * time.Minute},
+		{"0", 0, 0},
+		{"-5", 0, 0},
+		{"soon", 0, 0},
+		{time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat), 80 * time.Second, 90 * time.Second},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.value != "" {
+			h.Set("Retry-After", tt.value)
+		}
+		if d := parseRetryAfter(h); d < tt.min || d > tt.max {
+			t.Errorf("Retry-After %q: %s, ожидалось [%s, %s]", tt.value, d, tt.min, tt.max)
+		}
+	}
+}
+
+func TestRetryKind(t *testing.T) {
+	tests := []struct {
+		status     int
+		kind       ErrorKind
+		retryAfter time.Duration
+	}{
+		{http.StatusOK, KindUnknown, 0},
+		{http.StatusForbidden, KindUnknown, 0},
+		{http.StatusTooManyRequests, KindRateLimited, 7 * time.Second},
+		{http.StatusServiceUnavailable, KindServer, 7 * time.Second},
+		{http.StatusInternalServerError, KindServer, 0},
+		{http.StatusBadGateway, KindServer, 0},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{"Retry-After": {"7"}}}
+		kind, ra := retryKind(resp, nil)
+		if kind != tt.kind || ra != tt.retryAfter {
+			t.Errorf("%d: %s, %s; ожидалось %s, %s", tt.status, kind, ra, tt.kind, tt.retryAfter)
+		}
+	}
+
+	if kind, _ := retryKind(nil, &APIError{Kind: KindProxy}); kind != KindProxy {
+		t.Errorf("ошибка прокси: %s", kind)
+	}
+	if kind, _ := retryKind(nil, errors.Join(ErrCanceled, errors.New("context canceled"))); kind != KindUnknown {
+		t.Errorf("отмена повторяется: %s", kind)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	p := DefaultRetryPolicy()
+	want := map[ErrorKind]bool{
+		KindUnknown: false, KindAntiBot: false, KindUnauthorized: false, KindRateLimited: true,
+		KindServer: true, KindBadRequest: false, KindDecode: false, KindProxy: true,
+		KindTLS: true, KindNetwork: true,
+	}
+	for kind, ok := range want {
+		if p.Retryable(kind) != ok {
+			t.Errorf("Retryable(%s) = %v", kind, !ok)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package lenta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Schema — плоская схема JSON: путь → тип.
+// Путь строится через точку, элементы массива — "[]": items[].prices.price.
+// Типы: object, array, string, number, bool; any — содержимое не проверяется,
+// map — объект с произвольными ключами.
+
+type Schema map[string]string
+
+// DriftKind — вид расхождения схемы ответа.
+
+type DriftKind string
+
+const (
+	DriftUnknown DriftKind = "unknown" // поле есть в ответе, но не описано в модели
+	DriftMissing DriftKind = "missing" // обязательное поле модели ни разу не пришло
+	DriftType    DriftKind = "type"    // тип поля не совпадает с моделью
+	DriftNew     DriftKind = "new"     // поля нет в baseline
+	DriftGone    DriftKind = "gone"    // поле из baseline ни разу не пришло
+	DriftChanged DriftKind = "changed" // тип поля отличается от baseline
+)
+
+// ErrSchemaDrift — в строгом режиме ответ не совпал со схемой.
+var ErrSchemaDrift = errors.New("схема ответа изменилась")
+
+// SchemaDrift — одно расхождение схемы.
+
+type SchemaDrift struct {
+	Endpoint string    `json:"endpoint"`
+	Path     string    `json:"path"`
+	Kind     DriftKind `json:"kind"`
+	Expected string    `json:"expected,omitempty"`
+	Actual   string    `json:"actual,omitempty"`
+	Count    int       `json:"count,omitempty"` // в скольких ответах встретилось
+}
+
+func (d SchemaDrift) String() string {
+	s := fmt.Sprintf("%s %s: %s", d.Endpoint, d.Kind, d.Path)
+	if d.Expected != "" || d.Actual != "" {
+		s += fmt.Sprintf(" (ожидался %s, пришёл %s)", orDash(d.Expected), orDash(d.Actual))
+	}
+	if d.Count > 1 {
+		s += fmt.Sprintf(" ×%d", d.Count)
+	}
+	return s
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+// SchemaTracker — режим декодера, который сверяет каждый ответ API
+// со схемой модели и с baseline прошлых запусков.
+//
+// Поля модели с omitempty и указатели считаются необязательными.
+// Если baseline задан, неописанные в модели поля, уже известные baseline,
+// не считаются расхождением — отчёт показывает только изменения API.
+// В строгом режиме ответ с расхождением возвращает ошибку
+// (errors.Is(err, ErrSchemaDrift)); missing и gone видны только в Report.
+
+type SchemaTracker struct {
+	Strict   bool
+	Baseline map[string]Schema // endpoint → схема; nil — сравнение только с моделью
+
+	mu       sync.Mutex
+	models   map[string]modelSchema
+	observed map[string]Schema
+	drifts   map[string]*SchemaDrift
+}
+
+type modelSchema struct {
+	schema   Schema
+	optional map[string]bool
+}
+
+// NewSchemaTracker создаёт трекер схемы; baseline может быть nil.
+func NewSchemaTracker(baseline map[string]Schema, strict bool) *SchemaTracker {
+	return &SchemaTracker{
+		Strict:   strict,
+		Baseline: baseline,
+		models:   make(map[string]modelSchema),
+		observed: make(map[string]Schema),
+		drifts:   make(map[string]*SchemaDrift),
+	}
+}
+
+// observe разбирает тело ответа endpoint и сверяет его со схемой модели model.
+// Тело, которое не является JSON, пропускается — его ошибку вернёт json.Unmarshal.
+
+func (t *SchemaTracker) observe(endpoint string, body []byte, model reflect.Type) error {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ms, ok := t.models[endpoint]
+	if !ok {
+		ms = modelSchema{schema: make(Schema), optional: make(map[string]bool)}
+		walkModel(model, "", ms.schema, ms.optional)
+		t.models[endpoint] = ms
+	}
+	seen, ok := t.observed[endpoint]
+	if !ok {
+		seen = make(Schema)
+		t.observed[endpoint] = seen
+	}
+	base := t.Baseline[endpoint]
+
+	// Один и тот же путь встречается во всех элементах массива —
+	// расхождение считается один раз на ответ.
+	found := make(map[string]SchemaDrift)
+	report := func(d SchemaDrift) {
+		found[d.Endpoint+"|"+d.Path+"|"+string(d.Kind)] = d
+	}
+
+	walkJSON(doc, "", func(path, typ string) {
+		if prev, ok := seen[path]; !ok || prev == "null" {
+			seen[path] = typ
+		}
+
+		exp, modelled := ms.schema[path]
+		switch {
+		case !modelled:
+			if ms.schema[parentPath(path)] == "object" && base[path] == "" {
+				report(SchemaDrift{Endpoint: endpoint, Path: path, Kind: DriftUnknown, Actual: typ})
+			}
+		case typ != "null" && !typeMatches(exp, typ):
+			report(SchemaDrift{Endpoint: endpoint, Path: path, Kind: DriftType, Expected: exp, Actual: typ})
+		}
+
+		if base == nil {
+			return
+		}
+		switch bt, ok := base[path]; {
+		case !ok:
+			if _, parentKnown := base[parentPath(path)]; parentKnown || parentPath(path) == "" {
+				report(SchemaDrift{Endpoint: endpoint, Path: path, Kind: DriftNew, Actual: typ})
+			}
+		case typ != "null" && bt != "null" && bt != typ:
+			report(SchemaDrift{Endpoint: endpoint, Path: path, Kind: DriftChanged, Expected: bt, Actual: typ})
+		}
+	})
+
+	var first *SchemaDrift
+	for key, d := range found {
+		if first == nil || d.Path < first.Path {
+			first = &d
+		}
+		if cur, ok := t.drifts[key]; ok {
+			cur.Count++
+			continue
+		}
+		d.Count = 1
+		t.drifts[key] = &d
+	}
+
+	if t.Strict && first != nil {
+		return fmt.Errorf("%w: %s", ErrSchemaDrift, first)
+	}
+	return nil
+}
+
+// Report возвращает все расхождения за запуск, включая missing и gone.
+// Отсортировано по endpoint, виду и пути.
+
+func (t *SchemaTracker) Report() []SchemaDrift {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []SchemaDrift
+	for _, d := range t.drifts {
+		out = append(out, *d)
+	}
+
+	for endpoint, seen := range t.observed {
+		// Поле считается пропавшим, только если его родитель приходил:
+		// пустая страница не должна давать missing на всю модель товара.
+		// Элементы массива («items[]») тоже не обязательны: массив бывает пустым.
+		parentSeen := func(path string) bool {
+			if strings.HasSuffix(path, "[]") {
+				return false
+			}
+			p := parentPath(path)
+			return p == "" || seen[p] != ""
+		}
+
+		ms := t.models[endpoint]
+		for path, exp := range ms.schema {
+			if path == "" || ms.optional[path] || seen[path] != "" || !parentSeen(path) {
+				continue
+			}
+			out = append(out, SchemaDrift{Endpoint: endpoint, Path: path, Kind: DriftMissing, Expected: exp})
+		}
+		for path, bt := range t.Baseline[endpoint] {
+			if seen[path] != "" || !parentSeen(path) {
+				continue
+			}
+			out = append(out, SchemaDrift{Endpoint: endpoint, Path: path, Kind: DriftGone, Expected: bt})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Endpoint != b.Endpoint {
+			return a.Endpoint < b.Endpoint
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Path < b.Path
+	})
+	return out
+}
+
+// Observed возвращает схемы, собранные за запуск, — из них строится новый baseline.
+func (t *SchemaTracker) Observed() map[string]Schema {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[string]Schema, len(t.observed))
+	for endpoint, s := range t.observed {
+		cp := make(Schema, len(s))
+		for k, v := range s {
+			cp[k] = v
+		}
+		out[endpoint] = cp
+	}
+	return out
+}
+
+// LoadSchemaBaseline читает baseline из JSON-файла.
+// Отсутствующий файл — не ошибка: возвращается nil (сравнение только с моделью).
+
+func LoadSchemaBaseline(path string) (map[string]Schema, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var baseline map[string]Schema
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("baseline %s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+// SaveSchemaBaseline сохраняет схемы в JSON-файл (tmp + rename).
+func SaveSchemaBaseline(path string, baseline map[string]Schema) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// checkSchema сверяет тело ответа со схемой модели.
+// Без Config.Schema проверка выключена.
+
+func (c *Client) checkSchema(resp *http.Response, body []byte, model interface{}) error {
+	if c.cfg.Schema == nil {
+		return nil
+	}
+	if err := c.cfg.Schema.observe(endpointOf(resp.Request), body, reflect.TypeOf(model)); err != nil {
+		return newDecodeError(resp, body, err)
+	}
+	return nil
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// walkModel строит ожидаемую схему по json-тегам модели.
+func walkModel(t reflect.Type, path string, s Schema, optional map[string]bool) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == rawMessageType {
+		s[path] = "any"
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s[path] = "object"
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" {
+				walkModel(f.Type, path, s, optional)
+				s[path] = "object"
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			p := joinPath(path, name)
+			if strings.Contains(opts, "omitempty") || f.Type.Kind() == reflect.Pointer {
+				optional[p] = true
+			}
+			walkModel(f.Type, p, s, optional)
+		}
+	case reflect.Slice, reflect.Array:
+		s[path] = "array"
+		walkModel(t.Elem(), path+"[]", s, optional)
+	case reflect.Map:
+		s[path] = "map"
+	case reflect.String:
+		s[path] = "string"
+	case reflect.Bool:
+		s[path] = "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		s[path] = "number"
+	default:
+		s[path] = "any"
+	}
+}
+
+// walkJSON обходит документ и вызывает fn для каждого пути, кроме корня.
+func walkJSON(v interface{}, path string, fn func(path, typ string)) {
+	if path != "" {
+		fn(path, jsonType(v))
+	}
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, child := range x {
+			walkJSON(child, joinPath(path, k), fn)
+		}
+	case []interface{}:
+		for _, child := range x {
+			walkJSON(child, path+"[]", fn)
+		}
+	}
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "bool"
+	default:
+		return "null"
+	}
+}
+
+func typeMatches(expected, actual string) bool {
+	switch expected {
+	case "any":
+		return true
+	case "map":
+		return actual == "object"
+	default:
+		return expected == actual
+	}
+}
+
+func joinPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// parentPath — путь родителя: items[].prices → items[], items[] → items.
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "[]") {
+		return strings.TrimSuffix(path, "[]")
+	}
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
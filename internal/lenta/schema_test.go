@@ -0,0 +1,91 @@
+package lenta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type schemaTestItem struct {
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Badge *string `json:"badge"`
+}
+
+type schemaTestPage struct {
+	Items []schemaTestItem `json:"items"`
+	Total int              `json:"total,omitempty"`
+}
+
+func TestSchemaTrackerModel(t *testing.T) {
+	tr := NewSchemaTracker(nil, false)
+	model := reflect.TypeOf(schemaTestPage{})
+	bodies := []string{
+		`{"items":[{"id":1,"name":"Молоко","extra":true},{"id":"2","name":"Хлеб","extra":false}]}`,
+		`{"items":[{"id":3,"badge":null,"extra":true}]}`,
+		`не json`,
+	}
+	for _, b := range bodies {
+		if err := tr.observe("catalog/items", []byte(b), model); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type drift struct {
+		kind  DriftKind
+		path  string
+		count int
+	}
+	want := []drift{
+		{DriftType, "items[].id", 1},
+		{DriftUnknown, "items[].extra", 2},
+	}
+	got := tr.Report()
+	if len(got) != len(want) {
+		t.Fatalf("расхождений %d, ожидалось %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Kind != w.kind || got[i].Path != w.path || got[i].Count != w.count {
+			t.Errorf("расхождение %d: %v, ожидалось %s %s ×%d", i, got[i], w.kind, w.path, w.count)
+		}
+	}
+}
+
+// TestSchemaTrackerMissing — missing только для обязательных полей и только
+// если приходил их родитель: пустая страница не даёт missing на весь товар.
+func TestSchemaTrackerMissing(t *testing.T) {
+	tr := NewSchemaTracker(nil, false)
+	model := reflect.TypeOf(schemaTestPage{})
+	tr.observe("empty", []byte(`{"items":[]}`), model)
+	tr.observe("catalog/items", []byte(`{"items":[{"id":1}]}`), model)
+
+	got := tr.Report()
+	if len(got) != 1 || got[0].Endpoint != "catalog/items" || got[0].Kind != DriftMissing || got[0].Path != "items[].name" {
+		t.Errorf("Report() = %v, ожидался missing items[].name", got)
+	}
+}
+
+func TestSchemaTrackerBaseline(t *testing.T) {
+	baseline := map[string]Schema{"catalog/items": {
+		"items": "array", "items[]": "object", "items[].id": "number",
+		"items[].name": "string", "items[].old": "string",
+	}}
+	tr := NewSchemaTracker(baseline, true)
+	model := reflect.TypeOf(schemaTestPage{})
+
+	err := tr.observe("catalog/items", []byte(`{"items":[{"id":1,"name":"Молоко","badge":"-10%"}]}`), model)
+	if !errors.Is(err, ErrSchemaDrift) {
+		t.Fatalf("строгий режим: err = %v, ожидалась ErrSchemaDrift", err)
+	}
+
+	kinds := make(map[string]DriftKind)
+	for _, d := range tr.Report() {
+		kinds[d.Path] = d.Kind
+	}
+	if kinds["items[].badge"] != DriftNew || kinds["items[].old"] != DriftGone || len(kinds) != 2 {
+		t.Errorf("Report() = %v", tr.Report())
+	}
+	if obs := tr.Observed()["catalog/items"]; obs["items[].badge"] != "string" {
+		t.Errorf("Observed() = %v", obs)
+	}
+}
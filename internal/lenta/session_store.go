@@ -0,0 +1,146 @@
+package lenta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// probeCategoryID — категория для проверочного запроса сохранённой сессии.
+const probeCategoryID = 128
+
+// savedSession — формат файла сессии.
+
+type savedSession struct {
+	SavedAt time.Time `json:"savedAt"`
+	Session
+}
+
+// SaveSession сохраняет сессию в JSON-файл.
+// Файл содержит токены, поэтому создаётся с правами 0600.
+
+func SaveSession(path string, s *Session) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(savedSession{SavedAt: time.Now(), Session: *s}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSession читает сессию из файла и отбрасывает истёкшие cookies.
+// Если файла нет, возвращается ошибка, удовлетворяющая errors.Is(err, os.ErrNotExist).
+
+func LoadSession(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var saved savedSession
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("повреждённый файл сессии %s: %w", path, err)
+	}
+
+	now := time.Now()
+	sess := saved.Session
+	sess.Cookies = sess.Cookies[:0]
+	for _, c := range saved.Cookies {
+		if c.Expires.IsZero() || c.Expires.After(now) {
+			sess.Cookies = append(sess.Cookies, c)
+		}
+	}
+
+	if len(sess.Cookies) == 0 {
+		return nil, fmt.Errorf("все cookies сессии %s истекли", path)
+	}
+	if sess.SessionToken == "" {
+		return nil, fmt.Errorf("в сессии %s нет Utk_SessionToken", path)
+	}
+	return &sess, nil
+}
+
+// ProbeSession — ProbeSessionContext с context.Background().
+
+func ProbeSession(client *Client) error {
+	return ProbeSessionContext(context.Background(), client)
+}
+
+// ProbeSessionContext проверяет, что сессия клиента принимается API:
+// выполняет дешёвый запрос одной позиции каталога.
+// Отказ в сессии — ошибка вида KindUnauthorized или KindAntiBot;
+// прочие ошибки (сеть, прокси, 5xx, 429) о сессии ничего не говорят.
+
+func ProbeSessionContext(ctx context.Context, client *Client) error {
+	_, err := FetchCategoryContext(ctx, client, probeCategoryID, 0, 1)
+	switch {
+	case err == nil:
+		return nil
+	case sessionRejected(err):
+		return fmt.Errorf("сессия отклонена: %w", err)
+	}
+	return fmt.Errorf("проверка сессии: %w", err)
+}
+
+// sessionRejected — API отказал в сессии: её нужно прогреть заново.
+func sessionRejected(err error) bool {
+	kind := KindOf(err)
+	return kind == KindUnauthorized || kind == KindAntiBot
+}
+
+// RestoreOrWarm поднимает сессию клиента:
+//  1. загружает сессию из path и проверяет её пробным запросом;
+//  2. если файла нет, сессия истекла или отклонена — прогревает новую через warmer
+//     и сохраняет её в path.
+//
+// Другие ошибки проверки (сеть, прокси, 5xx, 429, дрейф схемы) возвращаются
+// как есть: браузер их не исправит. Пустой path отключает кеш — всегда
+// выполняется прогрев.
+
+func RestoreOrWarm(ctx context.Context, client *Client, warmer SessionWarmer, path string) error {
+	if path != "" {
+		sess, err := LoadSession(path)
+		switch {
+		case err == nil:
+			client.ApplySession(sess)
+			probeErr := ProbeSessionContext(ctx, client)
+			if probeErr == nil {
+				log.Printf("Сессия восстановлена из %s — прогрев пропущен", path)
+				return nil
+			}
+			if !sessionRejected(probeErr) {
+				return probeErr
+			}
+			log.Printf("Сохранённая сессия не прошла проверку: %v", probeErr)
+		case errors.Is(err, os.ErrNotExist):
+		default:
+			log.Printf("Сохранённая сессия недоступна: %v", err)
+		}
+	}
+
+	sess, err := warmer.Warm(WithProxy(ctx, client.SessionProxy()))
+	if err != nil {
+		return fmt.Errorf("ошибка прогрева сессии: %w", err)
+	}
+	client.ApplySession(sess)
+
+	if path != "" {
+		if err := SaveSession(path, client.Session()); err != nil {
+			log.Printf("Не удалось сохранить сессию в %s: %v", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package lenta
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeWarmer struct {
+	calls int
+}
+
+func (w *fakeWarmer) Warm(ctx context.Context) (*Session, error) {
+	w.calls++
+	return &Session{
+		Cookies:      []*http.Cookie{{Name: "qrator_jsid", Value: "fresh", Domain: "lenta.com", Path: "/"}},
+		SessionToken: "fresh-token",
+	}, nil
+}
+
+func TestRestoreOrWarmProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantWarm  bool
+		wantError bool
+	}{
+		{name: "сессия жива", status: http.StatusOK, body: `{"items":[]}`},
+		{name: "401", status: http.StatusUnauthorized, body: `{}`, wantWarm: true},
+		{name: "403 Qrator", status: http.StatusForbidden, body: `<html>qrator</html>`, wantWarm: true},
+		{name: "503", status: http.StatusServiceUnavailable, body: `{}`, wantError: true},
+		{name: "429", status: http.StatusTooManyRequests, body: `{}`, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "session.json")
+			saved := &Session{
+				Cookies:      []*http.Cookie{{Name: "qrator_jsid", Value: "old", Domain: "lenta.com", Path: "/", Expires: time.Now().Add(time.Hour)}},
+				SessionToken: "old-token",
+			}
+			if err := SaveSession(path, saved); err != nil {
+				t.Fatal(err)
+			}
+
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.status == http.StatusForbidden {
+					w.Header().Set("Content-Type", "text/html")
+				} else {
+					w.Header().Set("Content-Type", "application/json")
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			warmer := &fakeWarmer{}
+
+			err := RestoreOrWarm(context.Background(), client, warmer, path)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("err = %v, ожидалась ошибка: %v", err, tt.wantError)
+			}
+			if (warmer.calls > 0) != tt.wantWarm {
+				t.Errorf("прогрев вызван %d раз, ожидался: %v", warmer.calls, tt.wantWarm)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package lenta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialSOCKS5 открывает туннель до targetHost через SOCKS5-прокси.
+//
+// socks5://  — имя хоста резолвится локально, прокси получает IP.
+// socks5h:// — имя хоста передаётся прокси, DNS резолвится на его стороне
+//              (DNS-запросы не уходят с нашего IP).
+// Логин/пароль из URL передаются как username/password auth (RFC 1929).
+
+func dialSOCKS5(ctx context.Context, proxyURL *url.URL, targetHost string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if proxyURL.User != nil {
+		pass, _ := proxyURL.User.Password()
+		auth = &proxy.Auth{User: proxyURL.User.Username(), Password: pass}
+	}
+
+	forward := &net.Dialer{Timeout: 15 * time.Second}
+	d, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, forward)
+	if err != nil {
+		return nil, err
+	}
+
+	addr := targetHost
+	if proxyURL.Scheme == "socks5" {
+		if addr, err = resolveLocal(ctx, targetHost); err != nil {
+			return nil, err
+		}
+	}
+
+	cd, ok := d.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("SOCKS5 dialer не поддерживает контекст")
+	}
+	conn, err := cd.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("SOCKS5 %s: %w", proxyURL.Host, err)
+	}
+	return conn, nil
+}
+
+// resolveLocal заменяет имя хоста в host:port на первый найденный IP.
+func resolveLocal(ctx context.Context, hostport string) (string, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return hostport, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("нет адресов для %s", host)
+	}
+	return net.JoinHostPort(addrs[0].IP.String(), port), nil
+}
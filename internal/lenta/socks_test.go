@@ -0,0 +1,250 @@
+package lenta
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// socksRequest — что тестовый SOCKS5-сервер получил в CONNECT.
+type socksRequest struct {
+	atyp byte
+	host string
+	port int
+}
+
+// startSOCKS5 поднимает минимальный SOCKS5-сервер (RFC 1928) на 127.0.0.1.
+// Если user не пуст, требуется username/password auth (RFC 1929).
+// После CONNECT сервер работает как эхо — туннель проверяется без сети.
+
+func startSOCKS5(t *testing.T, user, pass string) (addr string, requests <-chan socksRequest) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	reqs := make(chan socksRequest, 8)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				req, err := serveSOCKS5(conn, user, pass)
+				if err != nil {
+					return
+				}
+				reqs <- req
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln.Addr().String(), reqs
+}
+
+func serveSOCKS5(conn net.Conn, user, pass string) (socksRequest, error) {
+	var req socksRequest
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return req, err
+	}
+	methods := make([]byte, head[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return req, err
+	}
+
+	want := byte(0x00)
+	if user != "" {
+		want = 0x02
+	}
+	offered := false
+	for _, m := range methods {
+		offered = offered || m == want
+	}
+	if !offered {
+		conn.Write([]byte{0x05, 0xff})
+		return req, errors.New("нет подходящего метода")
+	}
+	conn.Write([]byte{0x05, want})
+
+	if want == 0x02 {
+		gotUser, gotPass, err := readUserPass(conn)
+		if err != nil {
+			return req, err
+		}
+		if gotUser != user || gotPass != pass {
+			conn.Write([]byte{0x01, 0x01})
+			return req, errors.New("неверный логин")
+		}
+		conn.Write([]byte{0x01, 0x00})
+	}
+
+	hdr := make([]byte, 4) // VER CMD RSV ATYP
+	if _, err := io.ReadFull(conn, hdr); err != nil {
+		return req, err
+	}
+	req.atyp = hdr[3]
+	switch req.atyp {
+	case 0x01, 0x04:
+		ip := make(net.IP, 4)
+		if req.atyp == 0x04 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return req, err
+		}
+		req.host = ip.String()
+	case 0x03:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return req, err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return req, err
+		}
+		req.host = string(name)
+	default:
+		return req, errors.New("неизвестный ATYP")
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return req, err
+	}
+	req.port = int(binary.BigEndian.Uint16(port))
+
+	_, err := conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	return req, err
+}
+
+func readUserPass(conn net.Conn) (string, string, error) {
+	read := func() (string, error) {
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(conn, n); err != nil {
+			return "", err
+		}
+		b := make([]byte, n[0])
+		_, err := io.ReadFull(conn, b)
+		return string(b), err
+	}
+	ver := make([]byte, 1)
+	if _, err := io.ReadFull(conn, ver); err != nil {
+		return "", "", err
+	}
+	user, err := read()
+	if err != nil {
+		return "", "", err
+	}
+	pass, err := read()
+	return user, pass, err
+}
+
+// checkTunnel проверяет, что данные проходят через туннель в обе стороны.
+func checkTunnel(t *testing.T, conn net.Conn) {
+	t.Helper()
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "ping" {
+		t.Fatalf("через туннель получено %q", buf)
+	}
+}
+
+func TestDialSOCKS5(t *testing.T) {
+	tests := []struct {
+		name       string
+		scheme     string
+		user, pass string
+		target     string
+		wantATYP   byte // 0 — любой IP (IPv4 или IPv6)
+		wantHost   string
+	}{
+		{name: "socks5h без авторизации", scheme: "socks5h", target: "lenta.com:443", wantATYP: 0x03, wantHost: "lenta.com"},
+		{name: "socks5h с логином", scheme: "socks5h", user: "user", pass: "secret", target: "lenta.com:443", wantATYP: 0x03, wantHost: "lenta.com"},
+		{name: "socks5 резолвит локально", scheme: "socks5", target: "localhost:443"},
+		{name: "socks5 с IP", scheme: "socks5", user: "user", pass: "secret", target: "127.0.0.1:8443", wantATYP: 0x01, wantHost: "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, reqs := startSOCKS5(t, tt.user, tt.pass)
+			proxyURL := &url.URL{Scheme: tt.scheme, Host: addr}
+			if tt.user != "" {
+				proxyURL.User = url.UserPassword(tt.user, tt.pass)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dialSOCKS5(ctx, proxyURL, tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkTunnel(t, conn)
+
+			req := <-reqs
+			_, wantPort, _ := net.SplitHostPort(tt.target)
+			if strconv.Itoa(req.port) != wantPort {
+				t.Errorf("порт %d, ожидался %s", req.port, wantPort)
+			}
+			switch {
+			case tt.wantATYP == 0:
+				if req.atyp == 0x03 || net.ParseIP(req.host) == nil {
+					t.Errorf("прокси получил имя %q (ATYP %d), ожидался IP", req.host, req.atyp)
+				}
+			case req.atyp != tt.wantATYP || req.host != tt.wantHost:
+				t.Errorf("прокси получил %q (ATYP %d), ожидалось %q (ATYP %d)", req.host, req.atyp, tt.wantHost, tt.wantATYP)
+			}
+		})
+	}
+}
+
+func TestDialSOCKS5AuthRejected(t *testing.T) {
+	addr, _ := startSOCKS5(t, "user", "secret")
+	proxyURL := &url.URL{Scheme: "socks5h", Host: addr, User: url.UserPassword("user", "wrong")}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialSOCKS5(ctx, proxyURL, "lenta.com:443")
+	if err == nil {
+		conn.Close()
+		t.Fatal("подключение с неверным паролем прошло")
+	}
+}
+
+func TestDialViaProxySOCKS5(t *testing.T) {
+	addr, reqs := startSOCKS5(t, "", "")
+	proxyURL, err := url.Parse("socks5h://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := validateProxyURL(proxyURL); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialViaProxy(ctx, proxyURL, "lenta.com:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTunnel(t, conn)
+	if req := <-reqs; req.host != "lenta.com" {
+		t.Errorf("прокси получил %q", req.host)
+	}
+}
@@ -0,0 +1,253 @@
+package lenta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Значения по умолчанию для региона и магазина — как у браузера без выбранного адреса.
+const (
+	DefaultRegion      = "moscow"
+	DefaultRetailBrand = "lo"
+)
+
+// DeliveryMode — способ получения заказа (заголовок x-delivery-mode).
+// От него зависят ассортимент и цены.
+
+type DeliveryMode string
+
+const (
+	DeliveryPickup  DeliveryMode = "pickup"
+	DeliveryCourier DeliveryMode = "courier"
+)
+
+// ParseDeliveryMode разбирает способ получения из флага CLI ("" — pickup).
+func ParseDeliveryMode(s string) (DeliveryMode, error) {
+	switch m := DeliveryMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case DeliveryPickup, DeliveryCourier:
+		return m, nil
+	case "":
+		return DeliveryPickup, nil
+	default:
+		return "", fmt.Errorf("неизвестный способ получения: %q (pickup или courier)", s)
+	}
+}
+
+// Store — магазин сети.
+
+type Store struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address"`
+	City      string  `json:"city,omitempty"`
+	Region    string  `json:"domain,omitempty"` // значение для x-domain
+	Lat       float64 `json:"lat,omitempty"`
+	Lon       float64 `json:"lon,omitempty"`
+	IsPickup  bool    `json:"isPickup"`
+	IsCourier bool    `json:"isCourier"`
+}
+
+type storesResponse struct {
+	Items []Store `json:"items"`
+}
+
+// ListStores возвращает магазины региона (значение x-domain, например "spb").
+// Пустой region — регион клиента.
+
+func ListStores(ctx context.Context, client *Client, region string) ([]Store, error) {
+	if region == "" {
+		region = client.Region()
+	}
+	urlStr := client.BaseURL() + "/api-gateway/v1/stores?domain=" + url.QueryEscape(region)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.DoContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, canceled(ctx, err)
+		}
+		return nil, newTransportError(req, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newStatusError(resp, body)
+	}
+
+	var data storesResponse
+	if err := client.checkSchema(resp, body, data); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, newDecodeError(resp, body, err)
+	}
+	return data.Items, nil
+}
+
+// FindStores выбирает магазины по ID или по подстроке адреса/названия
+// без учёта регистра: "1234", "Невский", "пр. Победы 141".
+func FindStores(stores []Store, ref string) []Store {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, s := range stores {
+			if s.ID == id {
+				return []Store{s}
+			}
+		}
+		return nil
+	}
+
+	needle := strings.ToLower(ref)
+	var out []Store
+	for _, s := range stores {
+		if strings.Contains(strings.ToLower(s.Address), needle) || strings.Contains(strings.ToLower(s.Name), needle) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// StoreTarget — регион, способ получения и магазин для одного запроса.
+// Пустые поля берутся из Config клиента.
+
+type StoreTarget struct {
+	Label        string // подпись колонки в сравнении; "" — см. Name
+	Region       string
+	DeliveryMode DeliveryMode
+	StoreID      int
+}
+
+// Name — Label или "регион[:магазин][:способ]".
+func (t StoreTarget) Name() string {
+	if t.Label != "" {
+		return t.Label
+	}
+	name := t.Region
+	if name == "" {
+		name = DefaultRegion
+	}
+	if t.StoreID != 0 {
+		name += ":" + strconv.Itoa(t.StoreID)
+	}
+	if t.DeliveryMode != "" && t.DeliveryMode != DeliveryPickup {
+		name += ":" + string(t.DeliveryMode)
+	}
+	return name
+}
+
+// ParseStoreTarget разбирает цель вида "[метка=]регион[:магазин][:способ]":
+// "spb", "spb:1234", "kazan::courier", "Питер=spb:1234".
+func ParseStoreTarget(s string) (StoreTarget, error) {
+	var t StoreTarget
+	spec := strings.TrimSpace(s)
+	if label, rest, ok := strings.Cut(spec, "="); ok {
+		t.Label, spec = strings.TrimSpace(label), rest
+	}
+
+	parts := strings.Split(spec, ":")
+	if len(parts) > 3 {
+		return t, fmt.Errorf("цель %q: ожидается регион[:магазин][:способ]", s)
+	}
+	t.Region = strings.TrimSpace(parts[0])
+	if t.Region == "" {
+		return t, fmt.Errorf("цель %q: не задан регион", s)
+	}
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		id, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return t, fmt.Errorf("цель %q: неверный ID магазина", s)
+		}
+		t.StoreID = id
+	}
+	if len(parts) > 2 {
+		mode, err := ParseDeliveryMode(parts[2])
+		if err != nil {
+			return t, fmt.Errorf("цель %q: %w", s, err)
+		}
+		t.DeliveryMode = mode
+	}
+	return t, nil
+}
+
+type storeCtxKey struct{}
+
+// WithStoreTarget возвращает контекст, запросы с которым идут
+// в регион и магазин t вместо заданных в Config.
+func WithStoreTarget(ctx context.Context, t StoreTarget) context.Context {
+	return context.WithValue(ctx, storeCtxKey{}, t)
+}
+
+// StoreTargetFromContext возвращает цель, заданную через WithStoreTarget.
+func StoreTargetFromContext(ctx context.Context) (StoreTarget, bool) {
+	t, ok := ctx.Value(storeCtxKey{}).(StoreTarget)
+	return t, ok
+}
+
+// storeHeaders возвращает x-domain, x-delivery-mode и магазин для запроса.
+// Цель с регионом подменяет и магазин, даже если он не задан: магазин
+// из Config принадлежит другому региону и дал бы цены не того города.
+func (c *Client) storeHeaders(ctx context.Context) (region string, mode DeliveryMode, storeID int) {
+	region, mode, storeID = c.Region(), c.deliveryMode(), c.cfg.StoreID
+	if t, ok := StoreTargetFromContext(ctx); ok {
+		if t.Region != "" {
+			region, storeID = t.Region, t.StoreID
+		} else if t.StoreID != 0 {
+			storeID = t.StoreID
+		}
+		if t.DeliveryMode != "" {
+			mode = t.DeliveryMode
+		}
+	}
+	return region, mode, storeID
+}
+
+// Region возвращает регион клиента (x-domain).
+func (c *Client) Region() string {
+	if c.cfg.Region == "" {
+		return DefaultRegion
+	}
+	return c.cfg.Region
+}
+
+func (c *Client) deliveryMode() DeliveryMode {
+	if c.cfg.DeliveryMode == "" {
+		return DeliveryPickup
+	}
+	return c.cfg.DeliveryMode
+}
+
+func (c *Client) retailBrand() string {
+	if c.cfg.RetailBrand == "" {
+		return DefaultRetailBrand
+	}
+	return c.cfg.RetailBrand
+}
+
+// SetStore выбирает магазин для следующих запросов (0 — магазин региона по умолчанию).
+func (c *Client) SetStore(id int) {
+	c.refresher.mu.Lock()
+	defer c.refresher.mu.Unlock()
+	c.cfg.StoreID = id
+}
+
+// StoreID возвращает выбранный магазин (0 — не выбран).
+func (c *Client) StoreID() int {
+	c.refresher.mu.RLock()
+	defer c.refresher.mu.RUnlock()
+	return c.cfg.StoreID
+}
@@ -0,0 +1,41 @@
+package lenta
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStoreHeaders(t *testing.T) {
+	client, err := NewClient(&Config{Region: "moscow", StoreID: 1001, DeliveryMode: DeliveryPickup})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		target     string
+		wantRegion string
+		wantStore  int
+		wantMode   DeliveryMode
+	}{
+		{"", "moscow", 1001, DeliveryPickup},
+		{"spb", "spb", 0, DeliveryPickup}, // магазин Москвы не переносится в другой регион
+		{"spb:2002", "spb", 2002, DeliveryPickup},
+		{"kazan::courier", "kazan", 0, DeliveryCourier},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.target != "" {
+			target, err := ParseStoreTarget(tt.target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx = WithStoreTarget(ctx, target)
+		}
+		region, mode, store := client.storeHeaders(ctx)
+		if region != tt.wantRegion || store != tt.wantStore || mode != tt.wantMode {
+			t.Errorf("%q: получено %s/%d/%s, ожидалось %s/%d/%s",
+				tt.target, region, store, mode, tt.wantRegion, tt.wantStore, tt.wantMode)
+		}
+	}
+}
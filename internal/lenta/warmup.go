@@ -0,0 +1,352 @@
+package lenta
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Session — результат прогрева: всё, что нужно Client для прохождения anti-bot.
+
+type Session struct {
+	Cookies       []*http.Cookie `json:"cookies"`
+	SessionToken  string         `json:"sessionToken"` // Utk_SessionToken, уходит в заголовок sessiontoken
+	DeviceID      string         `json:"deviceId"`
+	UserSessionID string         `json:"userSessionId"`
+	Proxy         string         `json:"proxy,omitempty"` // прокси, через который прогрета сессия
+}
+
+// SessionWarmer получает валидную сессию (cookies + токены).
+// Основная реализация — PlaywrightWarmer, но интерфейс позволяет
+// подставить любой другой источник сессии.
+
+type SessionWarmer interface {
+	Warm(ctx context.Context) (*Session, error)
+}
+
+// WarmupStep — одна страница прогрева.
+
+type WarmupStep struct {
+	URL          string        // страница для перехода
+	WaitSelector string        // селектор, появления которого ждём (опционально)
+	Pause        time.Duration // пауза после загрузки страницы
+	Scroll       bool          // эмулировать скролл страницы
+}
+
+// DefaultWarmupSteps — сценарий прогрева для lenta.com:
+// главная инициирует anti-bot проверку Qrator, переход в категорию
+// завершает инициализацию frontend-сессии (часть токенов появляется только там).
+
+func DefaultWarmupSteps() []WarmupStep {
+	return []WarmupStep{
+		{URL: "https://lenta.com/", Pause: 6 * time.Second},
+		{URL: "https://lenta.com/catalog/moloko-128/", WaitSelector: `.card-name_content`, Pause: 8 * time.Second, Scroll: true},
+	}
+}
+
+// PlaywrightWarmer прогревает сессию в браузере через playwright-go.
+// Движок, UA, client hints, язык и viewport берутся из Profile —
+// тот же профиль должен стоять в Config.Profile клиента.
+// Поля можно менять после NewPlaywrightWarmer.
+
+type PlaywrightWarmer struct {
+	Headless      bool
+	Profile       *BrowserProfile
+	CookieURL     string // адрес, для которого забираются cookies
+	Steps         []WarmupStep
+	NavTimeout    time.Duration // таймаут перехода на страницу
+	WaitTimeout   time.Duration // таймаут ожидания WaitSelector
+	ScrollPause   time.Duration // пауза после каждого скролла
+	DeviceID      string        // пусто — сгенерировать
+	UserSessionID string        // пусто — сгенерировать
+}
+
+// NewPlaywrightWarmer создаёт warmer для профиля (nil — профиль по умолчанию).
+func NewPlaywrightWarmer(profile *BrowserProfile) *PlaywrightWarmer {
+	if profile == nil {
+		profile = defaultProfile()
+	}
+	return &PlaywrightWarmer{
+		Headless:    true,
+		Profile:     profile,
+		CookieURL:   baseURL,
+		Steps:       DefaultWarmupSteps(),
+		NavTimeout:  60 * time.Second,
+		WaitTimeout: 30 * time.Second,
+		ScrollPause: 4 * time.Second,
+	}
+}
+
+// Warm запускает headless браузер, проходит шаги прогрева и
+// возвращает cookies вместе с Utk_SessionToken.
+// Браузер и playwright закрываются до возврата.
+
+func (w *PlaywrightWarmer) Warm(ctx context.Context) (*Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, canceled(ctx, err)
+	}
+	log.Printf("Прогрев сессии через playwright-go (%s)...", w.Profile.Name)
+
+	pw, err := playwright.Run()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запуска playwright: %w", err)
+	}
+	defer pw.Stop()
+
+	// Прокси из контекста (WithProxy) — exit IP браузера должен совпадать с API-клиентом.
+	var proxy *playwright.Proxy
+	proxyURL := ProxyFromContext(ctx)
+	if proxyURL != nil {
+		// Chromium резолвит имена через SOCKS5 на стороне прокси — socks5h для него то же, что socks5.
+		scheme := proxyURL.Scheme
+		if scheme == "socks5h" {
+			scheme = "socks5"
+		}
+		proxy = &playwright.Proxy{Server: scheme + "://" + proxyURL.Host}
+		if proxyURL.User != nil {
+			if scheme == "socks5" {
+				log.Printf("[WARN] Chromium не поддерживает авторизацию SOCKS5 — прогрев через %s может не пройти", proxyURL.Redacted())
+			}
+			pass, _ := proxyURL.User.Password()
+			proxy.Username = playwright.String(proxyURL.User.Username())
+			proxy.Password = playwright.String(pass)
+		}
+		log.Printf("Прогрев через прокси %s", proxyURL.Redacted())
+	}
+
+	profile := w.Profile
+	launch := playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(w.Headless),
+		Proxy:    proxy,
+	}
+
+	var browserType playwright.BrowserType
+	switch profile.Engine {
+	case "firefox":
+		browserType = pw.Firefox
+	case "webkit":
+		browserType = pw.WebKit
+	default:
+		browserType = pw.Chromium
+		launch.Args = []string{
+			"--disable-blink-features=AutomationControlled",
+			"--no-sandbox",
+			"--disable-infobars",
+			fmt.Sprintf("--window-size=%d,%d", profile.ViewportWidth, profile.ViewportHeight),
+			"--disable-gpu",
+		}
+	}
+
+	browser, err := browserType.Launch(launch)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запуска браузера: %w", err)
+	}
+	defer browser.Close()
+
+	// Goto и WaitFor не знают о ctx и ждали бы своих таймаутов —
+	// при отмене браузер закрывается сразу, и они возвращают ошибку.
+	stopClose := context.AfterFunc(ctx, func() { browser.Close() })
+	defer stopClose()
+
+	// Client hints реального Chromium выдают его собственную версию —
+	// переопределяем их значениями профиля, чтобы они совпадали с UA и TLS.
+	bctx, err := browser.NewContext(playwright.BrowserNewContextOptions{
+		UserAgent: playwright.String(profile.UserAgent),
+		Viewport: &playwright.Size{
+			Width:  profile.ViewportWidth,
+			Height: profile.ViewportHeight,
+		},
+		IsMobile:         playwright.Bool(profile.IsMobile),
+		HasTouch:         playwright.Bool(profile.IsMobile),
+		Locale:           playwright.String(profile.Locale),
+		ExtraHttpHeaders: profile.clientHints(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания контекста: %w", err)
+	}
+	defer bctx.Close()
+
+	page, err := bctx.NewPage()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания страницы: %w", err)
+	}
+
+	for _, step := range w.Steps {
+		if err := w.runStep(ctx, page, step); err != nil {
+			return nil, err
+		}
+	}
+
+	// Cookies из браузера переносятся в cookiejar http.Client.
+	cookies, err := bctx.Cookies(w.CookieURL)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения куки: %w", err)
+	}
+
+	sess := &Session{
+		Cookies:       convertCookies(cookies),
+		DeviceID:      w.DeviceID,
+		UserSessionID: w.UserSessionID,
+	}
+	if proxyURL != nil {
+		sess.Proxy = proxyURL.String()
+	}
+	if sess.DeviceID == "" {
+		sess.DeviceID = uuid.New().String()
+	}
+	if sess.UserSessionID == "" {
+		sess.UserSessionID = uuid.New().String()
+	}
+
+	// Логируем важные куки
+	for _, ck := range sess.Cookies {
+		name := ck.Name
+		if strings.Contains(name, "qrator") || name == "Utk_SessionToken" || name == "UserSessionId" {
+			val := ck.Value
+			if len(val) > 16 {
+				val = val[:16] + "..."
+			}
+			log.Printf("PW Куки: %-20s = %s", name, val)
+		}
+		if name == "Utk_SessionToken" {
+			sess.SessionToken = ck.Value
+		}
+	}
+
+	return sess, nil
+}
+
+// runStep переходит на страницу шага, ждёт селектор и эмулирует поведение пользователя.
+// Ошибки навигации и ожидания не фатальны — anti-bot часто «подвешивает» networkidle.
+
+func (w *PlaywrightWarmer) runStep(ctx context.Context, page playwright.Page, step WarmupStep) error {
+	_, err := page.Goto(step.URL, playwright.PageGotoOptions{
+		Timeout:   playwright.Float(float64(w.NavTimeout.Milliseconds())),
+		WaitUntil: playwright.WaitUntilStateNetworkidle,
+	})
+	if err != nil {
+		log.Printf("Ошибка перехода на %s: %v", step.URL, err)
+	}
+	if err := sleepContext(ctx, step.Pause); err != nil {
+		return canceled(ctx, err)
+	}
+
+	if step.WaitSelector != "" {
+		err = page.Locator(step.WaitSelector).First().WaitFor(
+			playwright.LocatorWaitForOptions{
+				State:   playwright.WaitForSelectorStateVisible,
+				Timeout: playwright.Float(float64(w.WaitTimeout.Milliseconds())),
+			},
+		)
+		if err != nil {
+			log.Printf("Селектор %s не появился за %s: %v", step.WaitSelector, w.WaitTimeout, err)
+		}
+	}
+
+	// Скролл помогает завершить anti-bot challenge.
+	if step.Scroll {
+		for _, js := range []string{
+			`() => { window.scrollBy(0, document.body.scrollHeight / 2); }`,
+			`() => { window.scrollBy(0, document.body.scrollHeight); }`,
+		} {
+			if _, err := page.Evaluate(js, nil); err != nil {
+				log.Printf("Ошибка скролла: %v", err)
+			}
+			if err := sleepContext(ctx, w.ScrollPause); err != nil {
+				return canceled(ctx, err)
+			}
+		}
+	}
+	return nil
+}
+
+// convertCookies переводит cookies playwright в net/http.
+
+func convertCookies(cookies []playwright.Cookie) []*http.Cookie {
+	var httpCookies []*http.Cookie
+	for _, c := range cookies {
+		sameSite := http.SameSiteLaxMode
+
+		if c.SameSite != nil {
+			switch c.SameSite {
+			case playwright.SameSiteAttributeStrict:
+				sameSite = http.SameSiteStrictMode
+			case playwright.SameSiteAttributeLax:
+				sameSite = http.SameSiteLaxMode
+			case playwright.SameSiteAttributeNone:
+				sameSite = http.SameSiteNoneMode
+			}
+		}
+
+		// Expires = -1 у сессионных кук: нулевое время, иначе cookiejar сочтёт их истёкшими.
+		var expires time.Time
+		if c.Expires > 0 {
+			expires = time.Unix(int64(c.Expires), 0)
+		}
+
+		httpCookies = append(httpCookies, &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: sameSite,
+		})
+	}
+	return httpCookies
+}
+
+// ApplySession переносит сессию в клиент: cookies в jar, токены в конфиг.
+// Куки предыдущей сессии удаляются.
+
+func (c *Client) ApplySession(s *Session) {
+	c.jar.Reset()
+	c.SetCookies(s.Cookies)
+	c.pinProxy(s.Proxy)
+	if s.SessionToken != "" {
+		c.cfg.SessionToken = s.SessionToken
+	}
+	if s.DeviceID != "" {
+		c.cfg.DeviceID = s.DeviceID
+	}
+	if s.UserSessionID != "" {
+		c.cfg.UserSessionID = s.UserSessionID
+	}
+}
+
+// Session возвращает текущее состояние сессии клиента:
+// все cookies из jar (включая обновлённые сервером) и токены из конфига.
+
+func (c *Client) Session() *Session {
+	return &Session{
+		Cookies:       c.jar.Snapshot(),
+		SessionToken:  c.cfg.SessionToken,
+		DeviceID:      c.cfg.DeviceID,
+		UserSessionID: c.cfg.UserSessionID,
+		Proxy:         c.currentSessionProxy(),
+	}
+}
+
+// sleepContext — time.Sleep, прерываемый отменой контекста.
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,442 @@
+// Package storage хранит результаты обходов каталога в локальной SQLite:
+// товары, категории, магазины, запуски и историю цен.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"testJob/internal/lenta"
+
+	_ "modernc.org/sqlite" // драйвер "sqlite" без cgo
+)
+
+// schema — таблицы базы. Цены хранятся в копейках, как в lenta.Prices,
+// время — в UTC в формате RFC 3339.
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at    TEXT NOT NULL,
+	finished_at   TEXT,
+	region        TEXT NOT NULL,
+	delivery_mode TEXT NOT NULL,
+	store_id      INTEGER NOT NULL DEFAULT 0,
+	status        TEXT NOT NULL DEFAULT 'running',
+	products      INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+	id         INTEGER PRIMARY KEY,
+	parent_id  INTEGER,
+	name       TEXT NOT NULL DEFAULT '',
+	slug       TEXT NOT NULL DEFAULT '',
+	updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS stores (
+	id         INTEGER PRIMARY KEY,
+	region     TEXT NOT NULL DEFAULT '',
+	name       TEXT NOT NULL DEFAULT '',
+	address    TEXT NOT NULL DEFAULT '',
+	city       TEXT NOT NULL DEFAULT '',
+	updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS products (
+	id          INTEGER PRIMARY KEY,
+	name        TEXT NOT NULL,
+	slug        TEXT NOT NULL DEFAULT '',
+	brand       TEXT NOT NULL DEFAULT '',
+	country     TEXT NOT NULL DEFAULT '',
+	unit        TEXT NOT NULL DEFAULT '',
+	package     TEXT NOT NULL DEFAULT '',
+	category_id INTEGER,
+	first_seen  TEXT NOT NULL,
+	last_seen   TEXT NOT NULL,
+	raw         TEXT
+);
+
+CREATE TABLE IF NOT EXISTS price_observations (
+	run_id                INTEGER NOT NULL REFERENCES runs(id),
+	product_id            INTEGER NOT NULL REFERENCES products(id),
+	store_id              INTEGER NOT NULL DEFAULT 0,
+	region                TEXT NOT NULL,
+	observed_at           TEXT NOT NULL,
+	price                 INTEGER NOT NULL,
+	price_regular         INTEGER NOT NULL,
+	cost                  INTEGER NOT NULL,
+	is_loyalty_card_price INTEGER NOT NULL,
+	available             INTEGER NOT NULL,
+	PRIMARY KEY (run_id, product_id, store_id)
+);
+
+CREATE INDEX IF NOT EXISTS price_observations_product ON price_observations (product_id, observed_at);
+`
+
+// DB — база с историей обходов.
+
+type DB struct {
+	db *sql.DB
+}
+
+// Open открывает (и при необходимости создаёт) базу по пути path.
+
+func Open(path string) (*DB, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite допускает одного писателя — одно соединение исключает SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range []string{
+		"PRAGMA journal_mode = WAL",
+		"PRAGMA synchronous = NORMAL",
+		"PRAGMA foreign_keys = ON",
+		"PRAGMA busy_timeout = 5000",
+	} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%s: %w", pragma, err)
+		}
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("создание схемы: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+// Close закрывает базу.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Статусы запуска. Полный срез цен — только у RunDone: в остальных
+// часть категорий не обойдена, и их товары нельзя считать пропавшими.
+const (
+	RunRunning  = "running"
+	RunDone     = "done"
+	RunPartial  = "partial" // обход дошёл до конца, но часть категорий с ошибкой
+	RunFailed   = "failed"
+	RunCanceled = "canceled"
+)
+
+// Run — один запуск обхода.
+
+type Run struct {
+	ID           int64
+	StartedAt    time.Time
+	FinishedAt   time.Time // нулевое — запуск не завершён
+	Region       string
+	DeliveryMode string
+	StoreID      int
+	Status       string
+	Products     int // товаров с ценой; обновляется в FinishRun
+}
+
+// StartRun регистрирует новый запуск со статусом running.
+
+func (d *DB) StartRun(ctx context.Context, region string, mode lenta.DeliveryMode, storeID int) (*Run, error) {
+	run := &Run{
+		StartedAt:    time.Now().UTC(),
+		Region:       region,
+		DeliveryMode: string(mode),
+		StoreID:      storeID,
+		Status:       RunRunning,
+	}
+	res, err := d.db.ExecContext(ctx,
+		`INSERT INTO runs (started_at, region, delivery_mode, store_id, status) VALUES (?, ?, ?, ?, ?)`,
+		formatTime(run.StartedAt), run.Region, run.DeliveryMode, run.StoreID, run.Status)
+	if err != nil {
+		return nil, err
+	}
+	if run.ID, err = res.LastInsertId(); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// FinishRun сохраняет итог запуска: статус и число товаров с ценой в запуске.
+// Выполняется без контекста обхода, чтобы итог записался и после отмены.
+
+func (d *DB) FinishRun(run *Run, status string) error {
+	run.FinishedAt = time.Now().UTC()
+	run.Status = status
+	_, err := d.db.Exec(`
+		UPDATE runs SET finished_at = ?, status = ?,
+			products = (SELECT COUNT(*) FROM price_observations WHERE run_id = runs.id)
+		WHERE id = ?`,
+		formatTime(run.FinishedAt), run.Status, run.ID)
+	if err != nil {
+		return err
+	}
+	return d.db.QueryRow(`SELECT products FROM runs WHERE id = ?`, run.ID).Scan(&run.Products)
+}
+
+// ResumeRun возобновляет прерванный запуск id (продолжение по checkpoint-у).
+
+func (d *DB) ResumeRun(ctx context.Context, id int64) (*Run, error) {
+	run := &Run{ID: id}
+	var started string
+	err := d.db.QueryRowContext(ctx,
+		`SELECT started_at, region, delivery_mode, store_id, products FROM runs WHERE id = ?`, id).
+		Scan(&started, &run.Region, &run.DeliveryMode, &run.StoreID, &run.Products)
+	if err != nil {
+		return nil, fmt.Errorf("запуск #%d: %w", id, err)
+	}
+	run.StartedAt = parseTime(started)
+	run.Status = RunRunning
+
+	_, err = d.db.ExecContext(ctx, `UPDATE runs SET status = ?, finished_at = NULL WHERE id = ?`, run.Status, id)
+	if err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// Runs возвращает запуски, новые первыми. limit <= 0 — все.
+func (d *DB) Runs(ctx context.Context, limit int) ([]Run, error) {
+	q := `SELECT id, started_at, COALESCE(finished_at, ''), region, delivery_mode, store_id, status, products FROM runs ORDER BY id DESC`
+	if limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := d.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Run
+	for rows.Next() {
+		var r Run
+		var started, finished string
+		if err := rows.Scan(&r.ID, &started, &finished, &r.Region, &r.DeliveryMode, &r.StoreID, &r.Status, &r.Products); err != nil {
+			return nil, err
+		}
+		r.StartedAt = parseTime(started)
+		r.FinishedAt = parseTime(finished)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// SaveCategories сохраняет дерево категорий (upsert).
+
+func (d *DB) SaveCategories(ctx context.Context, tree *lenta.CategoryTree) error {
+	now := formatTime(time.Now().UTC())
+	return d.tx(ctx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO categories (id, parent_id, name, slug, updated_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				parent_id = excluded.parent_id,
+				name = CASE WHEN excluded.name != '' THEN excluded.name ELSE categories.name END,
+				slug = CASE WHEN excluded.slug != '' THEN excluded.slug ELSE categories.slug END,
+				updated_at = excluded.updated_at`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		var walk func(n *lenta.CategoryNode) error
+		walk = func(n *lenta.CategoryNode) error {
+			var parent interface{}
+			if n.Parent != nil {
+				parent = n.Parent.ID
+			}
+			if _, err := stmt.ExecContext(ctx, n.ID, parent, n.Name, n.Slug, now); err != nil {
+				return err
+			}
+			for _, ch := range n.Children {
+				if err := walk(ch); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, root := range tree.Roots {
+			if err := walk(root); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveStore сохраняет магазин (upsert). Пустые поля не затирают известные.
+
+func (d *DB) SaveStore(ctx context.Context, s lenta.Store) error {
+	_, err := d.db.ExecContext(ctx, `
+		INSERT INTO stores (id, region, name, address, city, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			region = CASE WHEN excluded.region != '' THEN excluded.region ELSE stores.region END,
+			name = CASE WHEN excluded.name != '' THEN excluded.name ELSE stores.name END,
+			address = CASE WHEN excluded.address != '' THEN excluded.address ELSE stores.address END,
+			city = CASE WHEN excluded.city != '' THEN excluded.city ELSE stores.city END,
+			updated_at = excluded.updated_at`,
+		s.ID, s.Region, s.Name, s.Address, s.City, formatTime(time.Now().UTC()))
+	return err
+}
+
+// SaveProducts записывает товары категории одной транзакцией:
+// обновляет карточки товаров и добавляет наблюдения цены в запуске run.
+// Магазин наблюдения — Product.StoreID, если API его вернул, иначе магазин запуска.
+
+func (d *DB) SaveProducts(ctx context.Context, run *Run, categoryID int, products []lenta.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	now := formatTime(time.Now().UTC())
+
+	return d.tx(ctx, func(tx *sql.Tx) error {
+		upsert, err := tx.PrepareContext(ctx, `
+			INSERT INTO products (id, name, slug, brand, country, unit, package, category_id, first_seen, last_seen, raw)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				name = excluded.name,
+				slug = excluded.slug,
+				brand = excluded.brand,
+				country = excluded.country,
+				unit = excluded.unit,
+				package = excluded.package,
+				category_id = excluded.category_id,
+				last_seen = excluded.last_seen,
+				raw = excluded.raw`)
+		if err != nil {
+			return err
+		}
+		defer upsert.Close()
+
+		observe, err := tx.PrepareContext(ctx, `
+			INSERT OR REPLACE INTO price_observations
+				(run_id, product_id, store_id, region, observed_at, price, price_regular, cost, is_loyalty_card_price, available)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer observe.Close()
+
+		for i := range products {
+			p := &products[i]
+			var raw interface{}
+			if len(p.Raw) > 0 {
+				raw = string(p.Raw)
+			}
+			if _, err := upsert.ExecContext(ctx, p.ID, p.Name, p.Slug, p.Brand.Name, p.Country, p.UnitOfMeasure,
+				p.Weight.Package, categoryID, now, now, raw); err != nil {
+				return fmt.Errorf("товар %d: %w", p.ID, err)
+			}
+
+			storeID := p.StoreID
+			if storeID == 0 {
+				storeID = run.StoreID
+			}
+			if _, err := observe.ExecContext(ctx, run.ID, p.ID, storeID, run.Region, now, p.Prices.Price,
+				p.Prices.PriceRegular, p.Prices.Cost, p.Prices.IsLoyaltyCardPrice, p.Stock.IsAvailable); err != nil {
+				return fmt.Errorf("цена товара %d: %w", p.ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// PricePoint — наблюдение цены товара.
+
+type PricePoint struct {
+	RunID              int64
+	ObservedAt         time.Time
+	StoreID            int
+	Region             string
+	Price              int
+	PriceRegular       int
+	Cost               int
+	IsLoyaltyCardPrice bool
+	Available          bool
+}
+
+// PriceHistory возвращает историю цен товара, старые наблюдения первыми.
+
+func (d *DB) PriceHistory(ctx context.Context, productID int) ([]PricePoint, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT run_id, observed_at, store_id, region, price, price_regular, cost, is_loyalty_card_price, available
+		FROM price_observations WHERE product_id = ? ORDER BY observed_at, run_id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PricePoint
+	for rows.Next() {
+		var p PricePoint
+		var observed string
+		if err := rows.Scan(&p.RunID, &observed, &p.StoreID, &p.Region, &p.Price, &p.PriceRegular,
+			&p.Cost, &p.IsLoyaltyCardPrice, &p.Available); err != nil {
+			return nil, err
+		}
+		p.ObservedAt = parseTime(observed)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// tx выполняет fn в транзакции: commit при успехе, rollback при ошибке.
+func (d *DB) tx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseTime(s string) time.Time {
+	if strings.TrimSpace(s) == "" {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
+
+// RunSnapshot возвращает цены запуска runID для сравнения с другим запуском.
+// Скидка определяется по price < price_regular (бейджи в базе не хранятся).
+
+func (d *DB) RunSnapshot(ctx context.Context, runID int64) (lenta.Snapshot, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT o.product_id, o.store_id, p.name, p.slug, o.price, o.price_regular
+		FROM price_observations o JOIN products p ON p.id = o.product_id
+		WHERE o.run_id = ?`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snap := make(lenta.Snapshot)
+	for rows.Next() {
+		var r lenta.PriceRecord
+		var slug string
+		if err := rows.Scan(&r.ProductID, &r.StoreID, &r.Name, &slug, &r.Price, &r.PriceRegular); err != nil {
+			return nil, err
+		}
+		r.URL = (&lenta.Product{Slug: slug}).URL()
+		snap[lenta.PriceKey{ProductID: r.ProductID, StoreID: r.StoreID}] = r
+	}
+	return snap, rows.Err()
+}
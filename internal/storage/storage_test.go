@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"testJob/internal/lenta"
+)
+
+func product(id, price, regular int) lenta.Product {
+	p := lenta.Product{ID: id, Name: "товар", Slug: "tovar", Prices: lenta.Prices{Price: price, PriceRegular: regular}}
+	p.Stock.IsAvailable = true
+	return p
+}
+
+func TestRunsAndPriceHistory(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(filepath.Join(t.TempDir(), "data", "prices.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Первый запуск: два товара в магазине запуска.
+	first, err := db.StartRun(ctx, "moscow", lenta.DeliveryPickup, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveProducts(ctx, first, 128, []lenta.Product{product(1, 10000, 10000), product(2, 5000, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FinishRun(first, RunDone); err != nil {
+		t.Fatal(err)
+	}
+	if first.Products != 2 {
+		t.Errorf("товаров в первом запуске %d, ожидалось 2", first.Products)
+	}
+
+	// Второй запуск прерван и продолжен: цена товара 1 снизилась,
+	// товар 3 пришёл с магазином из ответа API.
+	second, err := db.StartRun(ctx, "moscow", lenta.DeliveryPickup, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveProducts(ctx, second, 128, []lenta.Product{product(1, 8000, 10000)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FinishRun(second, RunCanceled); err != nil {
+		t.Fatal(err)
+	}
+	if second, err = db.ResumeRun(ctx, second.ID); err != nil {
+		t.Fatal(err)
+	}
+	p3 := product(3, 3000, 0)
+	p3.StoreID = 9
+	if err := db.SaveProducts(ctx, second, 129, []lenta.Product{p3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.FinishRun(second, RunDone); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := db.Runs(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != second.ID || runs[0].Status != RunDone || runs[0].Products != 2 || runs[0].FinishedAt.IsZero() {
+		t.Errorf("Runs() = %+v", runs)
+	}
+
+	history, err := db.PriceHistory(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].RunID != first.ID || history[0].Price != 10000 ||
+		history[1].RunID != second.ID || history[1].Price != 8000 || !history[1].Available {
+		t.Errorf("история цен товара 1: %+v", history)
+	}
+
+	snap, err := db.RunSnapshot(ctx, second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[lenta.PriceKey]int{{ProductID: 1, StoreID: 7}: 8000, {ProductID: 3, StoreID: 9}: 3000}
+	if len(snap) != len(want) {
+		t.Fatalf("снимок второго запуска: %+v", snap)
+	}
+	for key, price := range want {
+		if rec, ok := snap[key]; !ok || rec.Price != price || rec.URL == "" {
+			t.Errorf("снимок %+v: %+v", key, rec)
+		}
+	}
+	if !snap[lenta.PriceKey{ProductID: 1, StoreID: 7}].Discounted() {
+		t.Error("товар 1 ниже обычной цены, но не отмечен скидкой")
+	}
+}
+
+func TestResumeRunUnknown(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "prices.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ResumeRun(context.Background(), 42); err == nil {
+		t.Error("ResumeRun несуществующего запуска: ожидалась ошибка")
+	}
+}